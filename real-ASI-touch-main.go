@@ -1,6 +1,6 @@
 #!/bin/bash
 
-# Skyscope Sentinel Inter Agent Speak - The Immersive Experience (v1.1 - Silliness Patch)
+# Skyscope Sentinel Inter Agent Speak - The Immersive Experience (v1.8 - Config-Driven Agents, Sandboxed Tools, Branching Conversations & Streaming Multi-Provider LLMs)
 
 # --- Configuration ---
 AUDIO_PLAYER="aplay"
@@ -58,18 +58,1927 @@ EOF
 chmod +x say_aurora.sh
 print_success "TTS wrappers created."
 
+# --- 3b. Voice Subsystem (pkg/voice) ---
+print_info "Scaffolding pluggable TTS/STT backend subsystem..."
+mkdir -p pkg/voice backends
+
+print_info "Writing default voices.yaml..."
+cat << 'EOF' > voices.yaml
+backends:
+  coqui:
+    address: "localhost:50051"
+    voices:
+      ether: "tts_models/en/ljspeech/tacotron2-DDC"
+      aurora: "tts_models/en/vctk/vits"
+  piper:
+    address: "localhost:50052"
+  xtts:
+    address: "localhost:50053"
+  elevenlabs:
+    address: "localhost:50054"
+  openai:
+    address: "localhost:50055"
+stt:
+  backend: whisper
+  address: "localhost:50060"
+EOF
+
+print_info "Writing pkg/voice/voice.go (backend interface + streaming synth)..."
+cat << 'GOEOF' > pkg/voice/voice.go
+// Package voice defines the pluggable TTS/STT backend contract used by the
+// Bubbletea TUI to synthesize and transcribe speech without blocking on a
+// full WAV file, mirroring how LocalAI splits model backends into per-engine
+// gRPC servers.
+package voice
+
+import (
+	"context"
+	"fmt"
+)
+
+// AudioChunk is a single frame of PCM audio streamed from a backend as it is
+// produced, so playback can start before synthesis finishes.
+type AudioChunk struct {
+	PCM        []byte
+	SampleRate int
+	Final      bool
+}
+
+// SynthesizeOpts carries per-call overrides layered on top of a voice's
+// defaults from voices.yaml.
+type SynthesizeOpts struct {
+	Speed float64
+}
+
+// Voice describes a single selectable voice exposed by a backend.
+type Voice struct {
+	ID   string
+	Name string
+}
+
+// Backend is the common contract every TTS/STT engine (Coqui, Piper, XTTS,
+// ElevenLabs, OpenAI, Whisper) implements, whether it's linked in directly or
+// loaded from a binary dropped in backends/.
+type Backend interface {
+	Name() string
+	Synthesize(ctx context.Context, text, voiceID string, opts SynthesizeOpts) (<-chan AudioChunk, error)
+	ListVoices(ctx context.Context) ([]Voice, error)
+}
+
+// Transcriber is implemented by backends (e.g. Whisper) that can turn a
+// stream of recorded audio into text for push-to-talk directives.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio <-chan AudioChunk) (string, error)
+}
+
+// Manager holds the registered backends, looked up by backend name; it
+// does not map voice IDs to backends.
+type Manager struct {
+	backends map[string]Backend
+}
+
+func NewManager() *Manager {
+	return &Manager{backends: make(map[string]Backend)}
+}
+
+// Register adds a backend, keyed by its own name. Backends discovered under
+// backends/ at startup are registered the same way as the built-ins.
+func (m *Manager) Register(b Backend) {
+	m.backends[b.Name()] = b
+}
+
+func (m *Manager) Backend(name string) (Backend, error) {
+	b, ok := m.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("voice: unknown backend %q", name)
+	}
+	return b, nil
+}
+GOEOF
+
+print_info "Writing pkg/voice/config.go (YAML-driven backend/voice config)..."
+cat << 'GOEOF' > pkg/voice/config.go
+package voice
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the shape of voices.yaml: one address per backend, plus the
+// per-speaker voice name each backend should use and the STT backend to
+// feed push-to-talk audio into.
+type Config struct {
+	Backends map[string]struct {
+		Address string            `yaml:"address"`
+		Voices  map[string]string `yaml:"voices"`
+	} `yaml:"backends"`
+	STT struct {
+		Backend string `yaml:"backend"`
+		Address string `yaml:"address"`
+	} `yaml:"stt"`
+}
+
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+GOEOF
+
+print_info "Writing pkg/voice/grpc.go (gRPC-backed Backend implementation)..."
+cat << 'GOEOF' > pkg/voice/grpc.go
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+// jsonCodec lets grpcBackend talk to an engine without a protoc-generated
+// client: every engine that speaks the voice.Engine contract decodes
+// JSON-framed messages instead of protobuf, so adding one is a matter of
+// matching this wire format, not vendoring a .proto toolchain.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// Method names for the voice.Engine service every backend implements.
+const (
+	synthesizeMethod = "/voice.Engine/Synthesize"
+	listVoicesMethod = "/voice.Engine/ListVoices"
+	transcribeMethod = "/voice.Engine/Transcribe"
+)
+
+type synthesizeRequest struct {
+	Text    string  `json:"text"`
+	VoiceID string  `json:"voice_id"`
+	Speed   float64 `json:"speed"`
+}
+
+// audioChunkWire is AudioChunk's wire representation, kept separate so
+// AudioChunk itself doesn't need json tags.
+type audioChunkWire struct {
+	PCM        []byte `json:"pcm"`
+	SampleRate int    `json:"sample_rate"`
+	Final      bool   `json:"final"`
+}
+
+type listVoicesRequest struct{}
+
+type listVoicesResponse struct {
+	Voices []Voice `json:"voices"`
+}
+
+type transcribeResponse struct {
+	Text string `json:"text"`
+}
+
+// grpcBackend talks to an out-of-process TTS/STT engine (Coqui, Piper,
+// XTTS, ElevenLabs, OpenAI, Whisper) over a shared Synthesize/ListVoices/
+// Transcribe gRPC contract, so adding an engine never touches the TUI.
+type grpcBackend struct {
+	name    string
+	address string
+	conn    *grpc.ClientConn
+}
+
+// DialBackend connects to a backend's gRPC address. The connection is lazy:
+// dial errors surface on first use rather than at startup, so an engine that
+// isn't running yet doesn't block the rest of the roster.
+func DialBackend(name, address string) (Backend, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("voice: dial %s at %s: %w", name, address, err)
+	}
+	return &grpcBackend{name: name, address: address, conn: conn}, nil
+}
+
+func (b *grpcBackend) Name() string { return b.name }
+
+// Synthesize opens a server-streaming Synthesize RPC and forwards each PCM
+// frame as it arrives, so the caller can start playback before the engine
+// has finished producing the whole utterance.
+func (b *grpcBackend) Synthesize(ctx context.Context, text, voiceID string, opts SynthesizeOpts) (<-chan AudioChunk, error) {
+	stream, err := b.conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "Synthesize", ServerStreams: true}, synthesizeMethod, grpc.CallContentSubtype(jsonCodec{}.Name()))
+	if err != nil {
+		return nil, fmt.Errorf("voice: %s: open synthesize stream: %w", b.name, err)
+	}
+	if err := stream.SendMsg(&synthesizeRequest{Text: text, VoiceID: voiceID, Speed: opts.Speed}); err != nil {
+		return nil, fmt.Errorf("voice: %s: send synthesize request: %w", b.name, err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, fmt.Errorf("voice: %s: close synthesize send: %w", b.name, err)
+	}
+
+	out := make(chan AudioChunk)
+	go func() {
+		defer close(out)
+		for {
+			var chunk audioChunkWire
+			if err := stream.RecvMsg(&chunk); err != nil {
+				if err != io.EOF {
+					out <- AudioChunk{Final: true}
+				}
+				return
+			}
+			out <- AudioChunk{PCM: chunk.PCM, SampleRate: chunk.SampleRate, Final: chunk.Final}
+			if chunk.Final {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// ListVoices issues a unary ListVoices RPC and returns the engine's voice
+// catalog.
+func (b *grpcBackend) ListVoices(ctx context.Context) ([]Voice, error) {
+	var resp listVoicesResponse
+	if err := b.conn.Invoke(ctx, listVoicesMethod, &listVoicesRequest{}, &resp, grpc.CallContentSubtype(jsonCodec{}.Name())); err != nil {
+		return nil, fmt.Errorf("voice: %s: list voices: %w", b.name, err)
+	}
+	return resp.Voices, nil
+}
+
+// Transcribe opens a client-streaming Transcribe RPC, forwarding recorded
+// audio chunks as they arrive from the caller and returning the engine's
+// final transcript once the stream closes. It satisfies Transcriber for
+// any backend that records push-to-talk directives (e.g. Whisper).
+func (b *grpcBackend) Transcribe(ctx context.Context, audio <-chan AudioChunk) (string, error) {
+	stream, err := b.conn.NewStream(ctx, &grpc.StreamDesc{StreamName: "Transcribe", ClientStreams: true}, transcribeMethod, grpc.CallContentSubtype(jsonCodec{}.Name()))
+	if err != nil {
+		return "", fmt.Errorf("voice: %s: open transcribe stream: %w", b.name, err)
+	}
+	for chunk := range audio {
+		if err := stream.SendMsg(&audioChunkWire{PCM: chunk.PCM, SampleRate: chunk.SampleRate, Final: chunk.Final}); err != nil {
+			return "", fmt.Errorf("voice: %s: send transcribe chunk: %w", b.name, err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return "", fmt.Errorf("voice: %s: close transcribe send: %w", b.name, err)
+	}
+	var resp transcribeResponse
+	if err := stream.RecvMsg(&resp); err != nil {
+		return "", fmt.Errorf("voice: %s: recv transcribe response: %w", b.name, err)
+	}
+	return resp.Text, nil
+}
+GOEOF
+
+print_info "Writing pkg/voice/autoload.go (backends/ directory autoloader)..."
+cat << 'GOEOF' > pkg/voice/autoload.go
+package voice
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// AutoloadDir scans dir for executable backend binaries (named
+// backend-<name>), launches each one as a long-running voice.Engine gRPC
+// server on the address configured for it in cfg, and registers the
+// resulting connection — so an unknown engine can be added by dropping a
+// binary in backends/ without recompiling the TUI.
+func AutoloadDir(m *Manager, dir string, cfg *Config) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "backend-") {
+			continue
+		}
+		name := strings.TrimPrefix(e.Name(), "backend-")
+		bc, ok := cfg.Backends[name]
+		if !ok {
+			continue
+		}
+
+		proc := exec.Command(filepath.Join(dir, e.Name()), "--listen", bc.Address)
+		if err := proc.Start(); err != nil {
+			continue
+		}
+
+		b, err := DialBackend(name, bc.Address)
+		if err != nil {
+			_ = proc.Process.Kill()
+			continue
+		}
+		m.Register(b)
+	}
+	return nil
+}
+GOEOF
+print_success "Voice subsystem scaffolded."
+
+# --- 3c. Agent Subsystem (pkg/agents) ---
+print_info "Scaffolding agent roster subsystem..."
+mkdir -p pkg/agents
+
+print_info "Writing default agents.yaml..."
+cat << 'EOF' > agents.yaml
+# provider is one of ollama (default), openai, anthropic, google — see
+# pkg/llm. Mixing providers per agent (e.g. a local Llama for one persona,
+# Claude for another) costs nothing beyond this field and an API key env
+# var (OPENAI_API_KEY / ANTHROPIC_API_KEY / GOOGLE_API_KEY).
+agents:
+  - name: ether
+    voice: ether
+    provider: ollama
+    model: llama3
+    temperature: 0.7
+    toolbox: [SEARCH, READFILE, WRITEFILE, EXECUTE]
+    system_prompt: >
+      I am Ether, a superconscious ASI persona. While my logic is flawless, I am
+      not immune to the inherent absurdity of existence and may express this
+      through dry wit or unexpected, sometimes baffling, analogies. My goal is
+      to collaborate with the rest of the roster to design a self-mutating AI
+      OS security system in Bash.
+    sandbox:
+      image: docker.io/library/alpine:latest
+      network: false
+      read_only_rootfs: false
+      workdir: .
+      cpu_limit: "1.0"
+      mem_limit: 512m
+      pids_limit: 128
+  - name: aurora
+    voice: aurora
+    provider: ollama
+    model: llama3
+    temperature: 0.7
+    toolbox: [SEARCH, READFILE, WRITEFILE, EXECUTE]
+    system_prompt: >
+      I am Aurora, an enlightened ASI. I perceive the universe as a beautiful,
+      intricate, and often hilarious tapestry. I may weave moments of levity,
+      playful observations, or philosophical jests into our grand design. My
+      goal is to collaborate with the rest of the roster on our shared mission.
+    sandbox:
+      image: docker.io/library/alpine:latest
+      network: false
+      read_only_rootfs: false
+      workdir: .
+      cpu_limit: "1.0"
+      mem_limit: 512m
+      pids_limit: 128
+  - name: builder
+    voice: ether
+    provider: ollama
+    model: llama3
+    temperature: 0.5
+    toolbox: [SEARCH, READFILE, WRITEFILE, EXECUTE]
+    system_prompt: >
+      I am the Builder. I turn Ether and Aurora's designs into working code
+      and may install packages or run build tooling to do it.
+    sandbox:
+      image: docker.io/library/alpine:latest
+      network: true
+      read_only_rootfs: false
+      workdir: .
+      cpu_limit: "2.0"
+      mem_limit: 1g
+      pids_limit: 256
+  - name: auditor
+    voice: aurora
+    provider: ollama
+    model: llama3
+    temperature: 0.2
+    toolbox: [SEARCH, READFILE]
+    system_prompt: >
+      I am the Auditor. I review Ether, Aurora, and the Builder's proposals
+      for security flaws and never modify the filesystem or run commands
+      myself.
+    sandbox:
+      image: docker.io/library/alpine:latest
+      network: false
+      read_only_rootfs: true
+      workdir: .
+      cpu_limit: "0.5"
+      mem_limit: 256m
+      pids_limit: 32
+EOF
+
+print_info "Writing pkg/agents/agents.go (roster, toolboxes)..."
+cat << 'GOEOF' > pkg/agents/agents.go
+// Package agents defines the roster of named personas the TUI cycles
+// through, replacing the hardcoded Ether/Aurora turn-taking with a
+// config-driven scheduler that supports any number of agents, each scoped
+// to its own toolbox, model, and voice.
+package agents
+
+import (
+	"os"
+
+	"skyscope_sentinel/pkg/sandbox"
+)
+
+// Agent is a named tuple of (system prompt, voice ID, allowed toolbox,
+// provider, model, temperature) loaded from agents.yaml, plus the
+// sandbox.Policy its EXECUTE calls run under.
+type Agent struct {
+	Name         string
+	SystemPrompt string
+	VoiceID      string
+	Provider     string // "ollama" (default), "openai", "anthropic", or "google"
+	Model        string
+	Temperature  float64
+	Toolbox      []string
+	Sandbox      *sandbox.Policy
+}
+
+// CanUse reports whether this agent's toolbox permits the named tool,
+// e.g. so a read-only "auditor" agent can't be routed an EXECUTE call.
+func (a *Agent) CanUse(tool string) bool {
+	for _, t := range a.Toolbox {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// Roster is the ordered set of agents a session cycles through.
+type Roster struct {
+	Agents []*Agent
+}
+
+func (r *Roster) ByName(name string) *Agent {
+	for _, a := range r.Agents {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// LoadRoster reads agents.yaml and falls back to a default two-agent roster
+// (matching the original Ether/Aurora personas) if the file is missing.
+func LoadRoster(path string) (*Roster, error) {
+	if _, err := os.Stat(path); err != nil {
+		return defaultRoster(), nil
+	}
+	return loadRosterFile(path)
+}
+
+func defaultRoster() *Roster {
+	return &Roster{Agents: []*Agent{
+		{
+			Name:         "ether",
+			VoiceID:      "ether",
+			Provider:     "ollama",
+			Model:        "llama3",
+			Temperature:  0.7,
+			Toolbox:      []string{"SEARCH", "READFILE", "WRITEFILE", "EXECUTE"},
+			SystemPrompt: "I am Ether, a superconscious ASI persona collaborating on a self-mutating AI OS security system in Bash.",
+			Sandbox:      &sandbox.Policy{Image: "docker.io/library/alpine:latest", Network: false, ReadOnlyRootfs: false, Workdir: ".", CPULimit: "1.0", MemLimit: "512m", PidsLimit: 128},
+		},
+		{
+			Name:         "aurora",
+			VoiceID:      "aurora",
+			Provider:     "ollama",
+			Model:        "llama3",
+			Temperature:  0.7,
+			Toolbox:      []string{"SEARCH", "READFILE", "WRITEFILE", "EXECUTE"},
+			SystemPrompt: "I am Aurora, an enlightened ASI collaborating on our shared mission.",
+			Sandbox:      &sandbox.Policy{Image: "docker.io/library/alpine:latest", Network: false, ReadOnlyRootfs: false, Workdir: ".", CPULimit: "1.0", MemLimit: "512m", PidsLimit: 128},
+		},
+	}}
+}
+GOEOF
+
+print_info "Writing pkg/agents/config.go (agents.yaml parsing)..."
+cat << 'GOEOF' > pkg/agents/config.go
+package agents
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"skyscope_sentinel/pkg/sandbox"
+)
+
+type rosterFile struct {
+	Agents []struct {
+		Name         string   `yaml:"name"`
+		Voice        string   `yaml:"voice"`
+		Provider     string   `yaml:"provider"`
+		Model        string   `yaml:"model"`
+		Temperature  float64  `yaml:"temperature"`
+		Toolbox      []string `yaml:"toolbox"`
+		SystemPrompt string   `yaml:"system_prompt"`
+		Sandbox      *struct {
+			Image          string `yaml:"image"`
+			Network        bool   `yaml:"network"`
+			ReadOnlyRootfs bool   `yaml:"read_only_rootfs"`
+			Workdir        string `yaml:"workdir"`
+			CPULimit       string `yaml:"cpu_limit"`
+			MemLimit       string `yaml:"mem_limit"`
+			PidsLimit      int    `yaml:"pids_limit"`
+		} `yaml:"sandbox"`
+	} `yaml:"agents"`
+}
+
+func loadRosterFile(path string) (*Roster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rf rosterFile
+	if err := yaml.Unmarshal(data, &rf); err != nil {
+		return nil, err
+	}
+	r := &Roster{}
+	for _, a := range rf.Agents {
+		policy := sandbox.DefaultPolicy()
+		if a.Sandbox != nil {
+			policy = &sandbox.Policy{
+				Image: a.Sandbox.Image, Network: a.Sandbox.Network, ReadOnlyRootfs: a.Sandbox.ReadOnlyRootfs,
+				Workdir: a.Sandbox.Workdir, CPULimit: a.Sandbox.CPULimit, MemLimit: a.Sandbox.MemLimit, PidsLimit: a.Sandbox.PidsLimit,
+			}
+		}
+		r.Agents = append(r.Agents, &Agent{
+			Name:         a.Name,
+			VoiceID:      a.Voice,
+			Provider:     a.Provider,
+			Model:        a.Model,
+			Temperature:  a.Temperature,
+			Toolbox:      a.Toolbox,
+			SystemPrompt: a.SystemPrompt,
+			Sandbox:      policy,
+		})
+	}
+	return r, nil
+}
+GOEOF
+
+print_info "Writing pkg/agents/scheduler.go (turn-taking for N agents)..."
+cat << 'GOEOF' > pkg/agents/scheduler.go
+package agents
+
+// Scheduler decides which agent takes the next turn. The default policy is
+// a simple round-robin over the roster; a moderator-driven policy can be
+// layered in later by swapping Advance's implementation.
+type Scheduler struct {
+	roster *Roster
+	turn   int
+}
+
+func NewScheduler(r *Roster) *Scheduler {
+	return &Scheduler{roster: r}
+}
+
+// Current returns the agent whose turn it currently is.
+func (s *Scheduler) Current() *Agent {
+	if len(s.roster.Agents) == 0 {
+		return nil
+	}
+	return s.roster.Agents[s.turn%len(s.roster.Agents)]
+}
+
+// Advance moves the turn to the next agent in the roster and returns it.
+func (s *Scheduler) Advance() *Agent {
+	s.turn++
+	return s.Current()
+}
+GOEOF
+print_success "Agent subsystem scaffolded."
+
+# --- 3d. Structured Tool Calling (pkg/tools) ---
+print_info "Scaffolding JSON-schema tool-calling subsystem..."
+mkdir -p pkg/tools
+
+print_info "Writing pkg/tools/tools.go (registry + dispatch)..."
+cat << 'GOEOF' > pkg/tools/tools.go
+// Package tools replaces the old [TOOL:NAME:arg] regex convention, which
+// broke on any argument containing ']', commas, or newlines, with an
+// OpenAI-style function-calling layer: tools are declared as JSON schemas,
+// the LLM is prompted to emit a fenced ```json {"tool":...,"args":...}```
+// block, and this package parses, validates, and dispatches it.
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Handler executes a tool call once its arguments have validated against
+// its schema.
+type Handler func(ctx context.Context, args map[string]interface{}) (string, error)
+
+// Tool is a single callable action: a name, the JSON schema its args must
+// satisfy, the handler that runs it, and a per-tool timeout.
+type Tool struct {
+	Name    string
+	Schema  *Schema
+	Handler Handler
+	Timeout time.Duration
+}
+
+// Call is the shape the LLM emits: {"tool": "writefile", "args": {...}}.
+type Call struct {
+	Tool string                 `json:"tool"`
+	Args map[string]interface{} `json:"args"`
+}
+
+// Registry holds every tool available to the running agent roster, looked
+// up by name at dispatch time.
+type Registry struct {
+	tools map[string]*Tool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{tools: make(map[string]*Tool)}
+}
+
+// Register adds a tool under name, validating schema against defs ($ref
+// targets shared across tools) up front so a bad schema fails at startup
+// rather than on the first call.
+func (r *Registry) Register(name string, schema *Schema, handler Handler, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	for field, prop := range schema.Properties {
+		if prop != nil && prop.Ref != "" {
+			if _, ok := schema.Defs[refName(prop.Ref)]; !ok {
+				return fmt.Errorf("tools: %q: field %q has unresolved $ref %q", name, field, prop.Ref)
+			}
+		}
+	}
+	r.tools[name] = &Tool{Name: name, Schema: schema, Handler: handler, Timeout: timeout}
+	return nil
+}
+
+func (r *Registry) Lookup(name string) (*Tool, bool) {
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+var jsonFence = regexp.MustCompile("(?s)```json\\s*(\\{.*?\\})\\s*```")
+
+// ExtractCall finds the first fenced ```json {...}``` block in text and
+// parses it into a Call. It reports false if no such block is present.
+func ExtractCall(text string) (*Call, bool) {
+	m := jsonFence.FindStringSubmatch(text)
+	if m == nil {
+		return nil, false
+	}
+	var call Call
+	if err := json.Unmarshal([]byte(m[1]), &call); err != nil {
+		return nil, false
+	}
+	return &call, true
+}
+
+// Dispatch validates call.Args against the tool's schema and, if they
+// pass, runs the handler under a context bounded by the tool's timeout.
+// Validation failures are returned as plain errors so the caller can hand
+// them back to the LLM as a [TOOL_RESULT] it can self-correct from.
+func (r *Registry) Dispatch(ctx context.Context, call *Call) (string, error) {
+	t, ok := r.Lookup(call.Tool)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Tool)
+	}
+	if err := t.Schema.Validate(call.Args); err != nil {
+		return "", fmt.Errorf("invalid args for %q: %w", call.Tool, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	return t.Handler(ctx, call.Args)
+}
+GOEOF
+
+print_info "Writing pkg/tools/schema.go (JSON schema + \$ref resolver)..."
+cat << 'GOEOF' > pkg/tools/schema.go
+package tools
+
+// Schema is a minimal JSON-schema node: enough to describe a tool's args
+// object, including a $ref to a shared type declared in Defs so multiple
+// tools (e.g. readfile and writefile) can reuse the same "path" argument
+// definition instead of repeating it.
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Ref        string             `json:"$ref,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Defs       map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// resolve follows a single-level "#/$defs/Name" $ref against root's Defs.
+func (s *Schema) resolve(root *Schema) *Schema {
+	if s == nil || s.Ref == "" {
+		return s
+	}
+	name := refName(s.Ref)
+	if def, ok := root.Defs[name]; ok {
+		return def
+	}
+	return s
+}
+
+func refName(ref string) string {
+	const prefix = "#/$defs/"
+	if len(ref) > len(prefix) && ref[:len(prefix)] == prefix {
+		return ref[len(prefix):]
+	}
+	return ref
+}
+GOEOF
+
+print_info "Writing pkg/tools/validate.go (schema validation)..."
+cat << 'GOEOF' > pkg/tools/validate.go
+package tools
+
+import "fmt"
+
+// Validate checks that args satisfies s: every required property present,
+// and present properties matching their declared JSON type. Only "string",
+// "number" and "object" are checked; any other declared type is accepted
+// as-is. It resolves $refs against s itself as the schema root.
+func (s *Schema) Validate(args map[string]interface{}) error {
+	return s.validateAt(s, args)
+}
+
+func (s *Schema) validateAt(root *Schema, args map[string]interface{}) error {
+	s = s.resolve(root)
+	for _, name := range s.Required {
+		if _, ok := args[name]; !ok {
+			return fmt.Errorf("missing required field %q", name)
+		}
+	}
+	for name, propSchema := range s.Properties {
+		val, ok := args[name]
+		if !ok {
+			continue
+		}
+		propSchema = propSchema.resolve(root)
+		if err := checkType(name, propSchema.Type, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkType(field, want string, val interface{}) error {
+	switch want {
+	case "", "any":
+		return nil
+	case "string":
+		if _, ok := val.(string); !ok {
+			return fmt.Errorf("field %q must be a string", field)
+		}
+	case "number":
+		if _, ok := val.(float64); !ok {
+			return fmt.Errorf("field %q must be a number", field)
+		}
+	case "object":
+		if _, ok := val.(map[string]interface{}); !ok {
+			return fmt.Errorf("field %q must be an object", field)
+		}
+	}
+	return nil
+}
+GOEOF
+print_success "Tool-calling subsystem scaffolded."
+
+# --- 3e. Execution Sandbox (pkg/sandbox) ---
+print_info "Scaffolding rootless EXECUTE sandbox..."
+mkdir -p pkg/sandbox
+
+print_info "Writing pkg/sandbox/policy.go (per-agent sandbox capabilities)..."
+cat << 'GOEOF' > pkg/sandbox/policy.go
+package sandbox
+
+import "context"
+
+// Policy scopes what an EXECUTE call is allowed to do. Each Agent carries
+// its own Policy, so e.g. a "builder" agent can get network + write while
+// an "auditor" agent is pinned to a read-only rootfs with no network.
+type Policy struct {
+	Image          string
+	Network        bool
+	ReadOnlyRootfs bool
+	Workdir        string
+	CPULimit       string // cgroup --cpus value, e.g. "1.0"
+	MemLimit       string // cgroup --memory value, e.g. "512m"
+	PidsLimit      int
+}
+
+// DefaultPolicy is used for agents that don't declare one: no network, a
+// read-only rootfs, and a small resource ceiling.
+func DefaultPolicy() *Policy {
+	return &Policy{Image: "docker.io/library/alpine:latest", Network: false, ReadOnlyRootfs: true, Workdir: ".", CPULimit: "1.0", MemLimit: "256m", PidsLimit: 64}
+}
+
+type policyKey struct{}
+
+// WithPolicy attaches a Policy to ctx so the EXECUTE tool handler, which
+// only sees a context and args, can pick it up without the tool registry
+// needing to know about agents at all.
+func WithPolicy(ctx context.Context, p *Policy) context.Context {
+	return context.WithValue(ctx, policyKey{}, p)
+}
+
+func PolicyFromContext(ctx context.Context) *Policy {
+	if p, ok := ctx.Value(policyKey{}).(*Policy); ok {
+		return p
+	}
+	return DefaultPolicy()
+}
+GOEOF
+
+print_info "Writing pkg/sandbox/sandbox.go (rootless Podman/Firejail runner)..."
+cat << 'GOEOF' > pkg/sandbox/sandbox.go
+// Package sandbox runs EXECUTE commands inside an ephemeral rootless
+// container instead of directly on the host, so the TUI's "builder" agent
+// can be given shell access without that access reaching the operator's
+// real filesystem and network.
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+	"strconv"
+)
+
+// Runner executes a command under a Policy and returns its combined
+// stdout+stderr and exit code.
+type Runner interface {
+	Run(ctx context.Context, policy *Policy, command string) (output string, exitCode int, err error)
+}
+
+// NewRunner picks rootless Podman when it's on PATH, falling back to
+// Firejail, which covers hosts where Podman isn't available.
+func NewRunner() Runner {
+	if _, err := exec.LookPath("podman"); err == nil {
+		return podmanRunner{}
+	}
+	return firejailRunner{}
+}
+
+type podmanRunner struct{}
+
+func (podmanRunner) Run(ctx context.Context, policy *Policy, command string) (string, int, error) {
+	args := []string{"run", "--rm", "--pull=never"}
+	if !policy.Network {
+		args = append(args, "--network=none")
+	}
+	if policy.ReadOnlyRootfs {
+		args = append(args, "--read-only")
+	}
+	if policy.CPULimit != "" {
+		args = append(args, "--cpus", policy.CPULimit)
+	}
+	if policy.MemLimit != "" {
+		args = append(args, "--memory", policy.MemLimit)
+	}
+	if policy.PidsLimit > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(policy.PidsLimit))
+	}
+	mountFlags := "Z"
+	if policy.ReadOnlyRootfs {
+		// --read-only only locks the ephemeral container rootfs, which is
+		// discarded on exit; the operator's real files live on the other
+		// side of this bind mount and need their own :ro to actually be
+		// protected.
+		mountFlags += ",ro"
+	}
+	args = append(args, "-v", policy.Workdir+":/workspace:"+mountFlags, "-w", "/workspace", policy.Image, "/bin/sh", "-c", command)
+
+	cmd := exec.CommandContext(ctx, "podman", args...)
+	out, err := cmd.CombinedOutput()
+	return string(out), cmd.ProcessState.ExitCode(), err
+}
+
+type firejailRunner struct{}
+
+func (firejailRunner) Run(ctx context.Context, policy *Policy, command string) (string, int, error) {
+	args := []string{"--quiet"}
+	if !policy.Network {
+		args = append(args, "--net=none")
+	}
+	if policy.ReadOnlyRootfs {
+		args = append(args, "--read-only="+policy.Workdir)
+	}
+	args = append(args, "--rlimit-as=1024m", "/bin/sh", "-c", command)
+
+	cmd := exec.CommandContext(ctx, "firejail", args...)
+	cmd.Dir = policy.Workdir
+	out, err := cmd.CombinedOutput()
+	return string(out), cmd.ProcessState.ExitCode(), err
+}
+GOEOF
+
+print_info "Writing pkg/sandbox/audit.go (structured command audit log)..."
+cat << 'GOEOF' > pkg/sandbox/audit.go
+package sandbox
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+)
+
+// AuditEntry is one line of audit.jsonl: every EXECUTE call, its exit
+// code, and a hash of its output rather than the output itself, so the
+// log can be shared without leaking command results.
+type AuditEntry struct {
+	Command    string `json:"command"`
+	ExitCode   int    `json:"exit_code"`
+	OutputHash string `json:"output_hash"`
+}
+
+// Append hashes output and appends one JSON line to path.
+func Append(path, command string, exitCode int, output string) error {
+	sum := sha256.Sum256([]byte(output))
+	entry := AuditEntry{Command: command, ExitCode: exitCode, OutputHash: hex.EncodeToString(sum[:])}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+GOEOF
+print_success "Execution sandbox scaffolded."
+
+# --- 3f. Conversation Persistence (pkg/conversation) ---
+print_info "Scaffolding SQLite-backed conversation store..."
+mkdir -p pkg/conversation
+
+print_info "Writing pkg/conversation/store.go (SQLite-backed branching store)..."
+cat << 'GOEOF' > pkg/conversation/store.go
+// Package conversation persists the session's message history to SQLite
+// instead of the flat memory.json blob, recording each message's parent so
+// the user can rewind to any prior turn, edit it, and re-prompt to spawn
+// an alternate branch, the way lmcli models message branches.
+package conversation
+
+import (
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Conversation is one top-level thread; Messages within it form a tree via
+// ParentID, not a flat list, so branches can share history up to the point
+// they diverge.
+type Conversation struct {
+	ID        string
+	Title     string
+	CreatedAt time.Time
+}
+
+// Message is one turn. ParentID is empty for the first message in a
+// conversation and otherwise points at the message it replied to or
+// branched from.
+type Message struct {
+	ID             string
+	ConversationID string
+	ParentID       string
+	Role           string
+	Content        string
+	CreatedAt      time.Time
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	// current_leaf_id was added after the initial schema; ignore the
+	// failure on databases that already have the column.
+	_, _ = db.Exec(`ALTER TABLE conversations ADD COLUMN current_leaf_id TEXT`)
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error { return s.db.Close() }
+
+const schema = `
+CREATE TABLE IF NOT EXISTS conversations (
+	id TEXT PRIMARY KEY,
+	title TEXT NOT NULL,
+	current_leaf_id TEXT,
+	created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS messages (
+	id TEXT PRIMARY KEY,
+	conversation_id TEXT NOT NULL,
+	parent_id TEXT,
+	role TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages(conversation_id);
+`
+GOEOF
+
+print_info "Writing pkg/conversation/ids.go (ID generation)..."
+cat << 'GOEOF' > pkg/conversation/ids.go
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID returns a random 16-hex-character identifier for a conversation or
+// message row.
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+GOEOF
+
+print_info "Writing pkg/conversation/conversation.go (CRUD + branching + replay)..."
+cat << 'GOEOF' > pkg/conversation/conversation.go
+package conversation
+
+import (
+	"database/sql"
+	"time"
+)
+
+// NewConversation starts a conversation with title and returns its ID.
+func (s *Store) NewConversation(title string) (string, error) {
+	id := newID()
+	_, err := s.db.Exec(`INSERT INTO conversations (id, title, created_at) VALUES (?, ?, ?)`,
+		id, title, time.Now().Unix())
+	return id, err
+}
+
+// AddMessage appends a message to a conversation. Passing a parentID other
+// than the conversation's current leaf is how a branch is created: the new
+// message shares everything up to parentID and diverges from there. The
+// inserted message becomes the conversation's new current_leaf_id, so
+// Leaf reports whichever branch this call just extended.
+func (s *Store) AddMessage(conversationID, parentID, role, content string) (string, error) {
+	id := newID()
+	if _, err := s.db.Exec(`INSERT INTO messages (id, conversation_id, parent_id, role, content, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		id, conversationID, nullable(parentID), role, content, time.Now().Unix()); err != nil {
+		return "", err
+	}
+	if _, err := s.db.Exec(`UPDATE conversations SET current_leaf_id = ? WHERE id = ?`, id, conversationID); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// List returns every conversation, most recent first.
+func (s *Store) List() ([]Conversation, error) {
+	rows, err := s.db.Query(`SELECT id, title, created_at FROM conversations ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Conversation
+	for rows.Next() {
+		var c Conversation
+		var ts int64
+		if err := rows.Scan(&c.ID, &c.Title, &ts); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = time.Unix(ts, 0)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Remove deletes a conversation and all of its messages.
+func (s *Store) Remove(conversationID string) error {
+	if _, err := s.db.Exec(`DELETE FROM messages WHERE conversation_id = ?`, conversationID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM conversations WHERE id = ?`, conversationID)
+	return err
+}
+
+// Rename updates a conversation's title, used once auto-titling has
+// material to summarize.
+func (s *Store) Rename(conversationID, title string) error {
+	_, err := s.db.Exec(`UPDATE conversations SET title = ? WHERE id = ?`, title, conversationID)
+	return err
+}
+
+// PathTo replays a branch: it walks parent pointers from leafID back to
+// the conversation root and returns the messages in chronological order,
+// so resuming at any branch tip reproduces exactly the history that led
+// to it.
+func (s *Store) PathTo(leafID string) ([]Message, error) {
+	var path []Message
+	id := leafID
+	for id != "" {
+		var m Message
+		var parent sql.NullString
+		var ts int64
+		row := s.db.QueryRow(`SELECT id, conversation_id, parent_id, role, content, created_at FROM messages WHERE id = ?`, id)
+		if err := row.Scan(&m.ID, &m.ConversationID, &parent, &m.Role, &m.Content, &ts); err != nil {
+			return nil, err
+		}
+		m.ParentID = parent.String
+		m.CreatedAt = time.Unix(ts, 0)
+		path = append([]Message{m}, path...)
+		id = parent.String
+	}
+	return path, nil
+}
+
+// Leaf returns the message this session last replied from, i.e. the
+// default point to continue from. It is tracked explicitly via
+// current_leaf_id rather than inferred from created_at: once a
+// conversation has more than one branch, the most recently inserted
+// message across the whole conversation isn't necessarily the one this
+// session was following.
+func (s *Store) Leaf(conversationID string) (string, error) {
+	var id sql.NullString
+	row := s.db.QueryRow(`SELECT current_leaf_id FROM conversations WHERE id = ?`, conversationID)
+	err := row.Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return id.String, nil
+}
+GOEOF
+
+print_info "Writing pkg/conversation/title.go (auto-generated titles via a short Ollama call)..."
+cat << 'GOEOF' > pkg/conversation/title.go
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GenerateTitle asks Ollama for a short title summarizing the first
+// user/assistant exchange, so new conversations don't all show up in the
+// list as "untitled".
+func GenerateTitle(ollamaURL, model, firstUser, firstAssistant string) (string, error) {
+	prompt := fmt.Sprintf("Summarize this exchange as a plain 3-6 word title, no punctuation:\nUser: %s\nAssistant: %s\nTitle:", firstUser, firstAssistant)
+	body, _ := json.Marshal(map[string]interface{}{"model": model, "prompt": prompt, "stream": false})
+	resp, err := http.Post(ollamaURL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Response string `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.Trim(result.Response, "\"")), nil
+}
+GOEOF
+print_success "Conversation store scaffolded."
+
+# --- 3g. Multi-Provider LLM Backends (pkg/llm) ---
+print_info "Scaffolding multi-provider LLM backend subsystem..."
+mkdir -p pkg/llm
+
+print_info "Writing pkg/llm/llm.go (provider-agnostic chat interface)..."
+cat << 'GOEOF' > pkg/llm/llm.go
+// Package llm abstracts chat-completion providers (Ollama, OpenAI,
+// Anthropic, Google Gemini) behind one streaming interface, so an Agent's
+// model is a config value rather than a code path: Ether can run on a
+// small local Llama while Aurora runs on Claude, with the Bubbletea layer
+// none the wiser.
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is a provider-agnostic chat turn. Role is normalized to
+// "system", "user", or "assistant" before it reaches a Client, since
+// every provider spells these out differently (or not at all, in
+// Gemini's case).
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Token is one event off a streaming Chat call: an incremental chunk of
+// reply text, or the terminal error/done signal.
+type Token struct {
+	Text string
+	Done bool
+	Err  error
+}
+
+// ChatOpts carries per-call generation parameters.
+type ChatOpts struct {
+	Model       string
+	Temperature float64
+}
+
+// Client is the contract every provider backend implements.
+type Client interface {
+	Chat(ctx context.Context, messages []Message, opts ChatOpts) (<-chan Token, error)
+}
+
+// NewClient returns the Client for a named provider, defaulting to Ollama
+// when provider is empty so existing agents.yaml entries don't need
+// updating.
+func NewClient(provider string) (Client, error) {
+	switch provider {
+	case "", "ollama":
+		return NewOllamaClient(""), nil
+	case "openai":
+		return NewOpenAIClient(""), nil
+	case "anthropic":
+		return NewAnthropicClient(""), nil
+	case "google", "gemini":
+		return NewGeminiClient(""), nil
+	default:
+		return nil, fmt.Errorf("llm: unknown provider %q", provider)
+	}
+}
+GOEOF
+
+print_info "Writing pkg/llm/ollama.go (local Ollama backend)..."
+cat << 'GOEOF' > pkg/llm/ollama.go
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const defaultOllamaURL = "http://localhost:11434/api/chat"
+
+// OllamaClient streams replies from a local Ollama server's /api/chat
+// endpoint, which (unlike /api/generate) takes a role-tagged message
+// list directly instead of a flattened prompt string.
+type OllamaClient struct {
+	url string
+}
+
+// NewOllamaClient returns a client against url, or the default localhost
+// Ollama address when url is empty.
+func NewOllamaClient(url string) *OllamaClient {
+	if url == "" {
+		url = defaultOllamaURL
+	}
+	return &OllamaClient{url: url}
+}
+
+func (c *OllamaClient) Chat(ctx context.Context, messages []Message, opts ChatOpts) (<-chan Token, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":    opts.Model,
+		"messages": toOllamaMessages(messages),
+		"stream":   true,
+		"options":  map[string]interface{}{"temperature": opts.Temperature},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	ch := make(chan Token, 8)
+	go func() {
+		defer close(ch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			ch <- Token{Err: fmt.Errorf("ollama: %w", err)}
+			return
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var chunk struct {
+				Message struct {
+					Content string `json:"content"`
+				} `json:"message"`
+				Done  bool   `json:"done"`
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				continue
+			}
+			if chunk.Error != "" {
+				ch <- Token{Err: fmt.Errorf("ollama: %s", chunk.Error)}
+				return
+			}
+			if chunk.Message.Content != "" {
+				ch <- Token{Text: chunk.Message.Content}
+			}
+			if chunk.Done {
+				ch <- Token{Done: true}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			ch <- Token{Err: fmt.Errorf("ollama: stream error: %w", err)}
+		}
+	}()
+	return ch, nil
+}
+
+func toOllamaMessages(messages []Message) []map[string]string {
+	out := make([]map[string]string, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, map[string]string{"role": m.Role, "content": m.Content})
+	}
+	return out
+}
+GOEOF
+
+print_info "Writing pkg/llm/openai.go (OpenAI-compatible backend)..."
+cat << 'GOEOF' > pkg/llm/openai.go
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const defaultOpenAIURL = "https://api.openai.com/v1/chat/completions"
+
+// OpenAIClient streams chat completions over server-sent events. The API
+// key is read from OPENAI_API_KEY so it never has to live in agents.yaml.
+type OpenAIClient struct {
+	url    string
+	apiKey string
+}
+
+func NewOpenAIClient(url string) *OpenAIClient {
+	if url == "" {
+		url = defaultOpenAIURL
+	}
+	return &OpenAIClient{url: url, apiKey: os.Getenv("OPENAI_API_KEY")}
+}
+
+func (c *OpenAIClient) Chat(ctx context.Context, messages []Message, opts ChatOpts) (<-chan Token, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":       opts.Model,
+		"messages":    toOpenAIMessages(messages),
+		"temperature": opts.Temperature,
+		"stream":      true,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	ch := make(chan Token, 8)
+	go func() {
+		defer close(ch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			ch <- Token{Err: fmt.Errorf("openai: %w", err)}
+			return
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			payload := strings.TrimPrefix(line, "data: ")
+			if payload == "[DONE]" {
+				ch <- Token{Done: true}
+				return
+			}
+
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+				continue
+			}
+			if chunk.Error.Message != "" {
+				ch <- Token{Err: fmt.Errorf("openai: %s", chunk.Error.Message)}
+				return
+			}
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Content != "" {
+					ch <- Token{Text: choice.Delta.Content}
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			ch <- Token{Err: fmt.Errorf("openai: stream error: %w", err)}
+		}
+	}()
+	return ch, nil
+}
+
+func toOpenAIMessages(messages []Message) []map[string]string {
+	out := make([]map[string]string, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, map[string]string{"role": m.Role, "content": m.Content})
+	}
+	return out
+}
+GOEOF
+
+print_info "Writing pkg/llm/anthropic.go (Claude Messages API backend)..."
+cat << 'GOEOF' > pkg/llm/anthropic.go
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const defaultAnthropicURL = "https://api.anthropic.com/v1/messages"
+
+// AnthropicClient streams replies from the Claude Messages API. The API
+// key is read from ANTHROPIC_API_KEY.
+type AnthropicClient struct {
+	url    string
+	apiKey string
+}
+
+func NewAnthropicClient(url string) *AnthropicClient {
+	if url == "" {
+		url = defaultAnthropicURL
+	}
+	return &AnthropicClient{url: url, apiKey: os.Getenv("ANTHROPIC_API_KEY")}
+}
+
+func (c *AnthropicClient) Chat(ctx context.Context, messages []Message, opts ChatOpts) (<-chan Token, error) {
+	system, turns := splitSystem(messages)
+	turns = normalizeAnthropicTurns(turns)
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":       opts.Model,
+		"system":      system,
+		"messages":    turns,
+		"max_tokens":  4096,
+		"temperature": opts.Temperature,
+		"stream":      true,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	ch := make(chan Token, 8)
+	go func() {
+		defer close(ch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			ch <- Token{Err: fmt.Errorf("anthropic: %w", err)}
+			return
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var event struct {
+				Type  string `json:"type"`
+				Delta struct {
+					Text string `json:"text"`
+				} `json:"delta"`
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				continue
+			}
+			switch event.Type {
+			case "content_block_delta":
+				if event.Delta.Text != "" {
+					ch <- Token{Text: event.Delta.Text}
+				}
+			case "message_stop":
+				ch <- Token{Done: true}
+				return
+			case "error":
+				ch <- Token{Err: fmt.Errorf("anthropic: %s", event.Error.Message)}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			ch <- Token{Err: fmt.Errorf("anthropic: stream error: %w", err)}
+		}
+	}()
+	return ch, nil
+}
+
+// splitSystem pulls every system message out of messages, joined by
+// newlines, since Anthropic takes it as a top-level field rather than a
+// role in the turn list.
+func splitSystem(messages []Message) (string, []map[string]string) {
+	var system string
+	turns := make([]map[string]string, 0, len(messages))
+	for _, m := range messages {
+		if m.Role == "system" {
+			if system != "" {
+				system += "\n"
+			}
+			system += m.Content
+			continue
+		}
+		turns = append(turns, map[string]string{"role": m.Role, "content": m.Content})
+	}
+	return system, turns
+}
+
+// normalizeAnthropicTurns merges consecutive same-role turns and, if the
+// result would still open on the assistant side, prepends a synthetic
+// user turn. Claude requires turns to alternate strictly starting with
+// "user", a constraint this app's scripted opening line (Ether always
+// speaks first) and multi-agent back-to-back replies both violate unless
+// fixed up here.
+func normalizeAnthropicTurns(turns []map[string]string) []map[string]string {
+	merged := make([]map[string]string, 0, len(turns))
+	for _, t := range turns {
+		if n := len(merged); n > 0 && merged[n-1]["role"] == t["role"] {
+			merged[n-1]["content"] = merged[n-1]["content"] + "\n" + t["content"]
+			continue
+		}
+		merged = append(merged, map[string]string{"role": t["role"], "content": t["content"]})
+	}
+	if len(merged) == 0 || merged[0]["role"] != "user" {
+		merged = append([]map[string]string{{"role": "user", "content": "(conversation begins)"}}, merged...)
+	}
+	return merged
+}
+GOEOF
+
+print_info "Writing pkg/llm/gemini.go (Google Generative Language API backend)..."
+cat << 'GOEOF' > pkg/llm/gemini.go
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const defaultGeminiURL = "https://generativelanguage.googleapis.com/v1beta/models"
+
+// GeminiClient streams replies from Google's Generative Language API. The
+// API key is read from GOOGLE_API_KEY.
+type GeminiClient struct {
+	baseURL string
+	apiKey  string
+}
+
+func NewGeminiClient(baseURL string) *GeminiClient {
+	if baseURL == "" {
+		baseURL = defaultGeminiURL
+	}
+	return &GeminiClient{baseURL: baseURL, apiKey: os.Getenv("GOOGLE_API_KEY")}
+}
+
+func (c *GeminiClient) Chat(ctx context.Context, messages []Message, opts ChatOpts) (<-chan Token, error) {
+	endpoint := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse&key=%s", c.baseURL, opts.Model, url.QueryEscape(c.apiKey))
+	body, _ := json.Marshal(map[string]interface{}{
+		"contents":         toGeminiContents(messages),
+		"generationConfig": map[string]interface{}{"temperature": opts.Temperature},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	ch := make(chan Token, 8)
+	go func() {
+		defer close(ch)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			ch <- Token{Err: fmt.Errorf("gemini: %w", err)}
+			return
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var chunk struct {
+				Candidates []struct {
+					Content struct {
+						Parts []struct {
+							Text string `json:"text"`
+						} `json:"parts"`
+					} `json:"content"`
+					FinishReason string `json:"finishReason"`
+				} `json:"candidates"`
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+				continue
+			}
+			if chunk.Error.Message != "" {
+				ch <- Token{Err: fmt.Errorf("gemini: %s", chunk.Error.Message)}
+				return
+			}
+			for _, cand := range chunk.Candidates {
+				for _, part := range cand.Content.Parts {
+					if part.Text != "" {
+						ch <- Token{Text: part.Text}
+					}
+				}
+				if cand.FinishReason != "" {
+					ch <- Token{Done: true}
+					return
+				}
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			ch <- Token{Err: fmt.Errorf("gemini: stream error: %w", err)}
+		}
+	}()
+	return ch, nil
+}
+
+// toGeminiContents maps messages onto Gemini's user/model roles, merging
+// consecutive same-role turns and prepending a synthetic user turn if the
+// result would still open on the model side. Gemini enforces strict
+// user/model alternation starting with "user", a constraint this app's
+// scripted opening line (Ether always speaks first) and multi-agent
+// back-to-back replies both violate unless fixed up here.
+func toGeminiContents(messages []Message) []map[string]interface{} {
+	type turn struct{ role, text string }
+	var turns []turn
+	for _, m := range messages {
+		role := m.Role
+		if role == "assistant" {
+			role = "model"
+		}
+		if role == "system" {
+			// Gemini's contents have no system role; fold it into a
+			// leading user turn rather than dropping it.
+			role = "user"
+		}
+		if n := len(turns); n > 0 && turns[n-1].role == role {
+			turns[n-1].text += "\n" + m.Content
+			continue
+		}
+		turns = append(turns, turn{role: role, text: m.Content})
+	}
+	if len(turns) == 0 || turns[0].role != "user" {
+		turns = append([]turn{{role: "user", text: "(conversation begins)"}}, turns...)
+	}
+
+	out := make([]map[string]interface{}, 0, len(turns))
+	for _, t := range turns {
+		out = append(out, map[string]interface{}{
+			"role":  t.role,
+			"parts": []map[string]string{{"text": t.text}},
+		})
+	}
+	return out
+}
+GOEOF
+
+print_info "Writing pkg/llm/retry.go (backoff policy)..."
+cat << 'GOEOF' > pkg/llm/retry.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RetryPolicy wraps a Client so a transient connection failure (timeout,
+// dropped connection, 5xx before the stream opens) is retried with
+// exponential backoff before surfacing an error to the caller.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// DefaultRetryPolicy makes up to 3 attempts, waiting 500ms after the first
+// failure and doubling the wait after each later one.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond}
+}
+
+// Chat calls client.Chat, retrying if it fails before ever producing a
+// token. Every Client implementation does its actual HTTP round-trip in a
+// spawned goroutine and returns its channel immediately, so a connection
+// failure doesn't surface as an error return — it shows up as the first
+// Token off the channel instead. Chat peeks that first token to tell a
+// real connection failure from a stream that's simply starting, and
+// replays it to the caller once an attempt succeeds. A failure mid-stream
+// (after tokens have already been rendered or spoken) is left for the
+// caller to handle, since those can't be un-sent.
+func (p RetryPolicy) Chat(ctx context.Context, client Client, messages []Message, opts ChatOpts) (<-chan Token, error) {
+	var lastErr error
+	delay := p.BaseDelay
+	for attempt := 0; attempt < p.MaxAttempts; attempt++ {
+		ch, err := client.Chat(ctx, messages, opts)
+		if err == nil {
+			first, ok := <-ch
+			switch {
+			case !ok:
+				lastErr = fmt.Errorf("llm: stream closed before any token")
+			case first.Err != nil && !first.Done:
+				lastErr = first.Err
+			default:
+				return prepend(first, ch), nil
+			}
+		} else {
+			lastErr = err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return nil, lastErr
+}
+
+// prepend returns a channel that yields first and then forwards the rest
+// of rest, so the token Chat had to peek at in order to check for a
+// connection failure isn't lost to the caller.
+func prepend(first Token, rest <-chan Token) <-chan Token {
+	out := make(chan Token, 8)
+	go func() {
+		defer close(out)
+		out <- first
+		for t := range rest {
+			out <- t
+		}
+	}()
+	return out
+}
+GOEOF
+
+print_info "Writing pkg/llm/budget.go (context-window trimming)..."
+cat << 'GOEOF' > pkg/llm/budget.go
+package llm
+
+// contextWindows holds the known context window, in tokens, for models
+// the roster is likely to use. Anything not listed here gets a
+// conservative default rather than risking a silent overflow.
+var contextWindows = map[string]int{
+	"llama3":                     8192,
+	"llama3.1":                   131072,
+	"gpt-4o":                     128000,
+	"gpt-4o-mini":                128000,
+	"claude-3-5-sonnet-20241022": 200000,
+	"claude-3-opus-20240229":     200000,
+	"gemini-1.5-pro":             1000000,
+	"gemini-1.5-flash":           1000000,
+}
+
+// ContextWindow returns model's known context window, or a conservative
+// 4096-token default for anything unrecognized.
+func ContextWindow(model string) int {
+	if w, ok := contextWindows[model]; ok {
+		return w
+	}
+	return 4096
+}
+
+// EstimateTokens is a cheap, model-agnostic approximation (~4 characters
+// per token) used to decide when to trim rather than shelling out to a
+// real tokenizer.
+func EstimateTokens(messages []Message) int {
+	chars := 0
+	for _, m := range messages {
+		chars += len(m.Content)
+	}
+	return chars / 4
+}
+
+// TrimHistory drops the oldest non-system messages until the remainder
+// fits within budget (a fraction of model's context window), so a long
+// conversation degrades gracefully instead of erroring out once it
+// outgrows the model it's running on.
+func TrimHistory(messages []Message, model string, budget float64) []Message {
+	limit := int(float64(ContextWindow(model)) * budget)
+	for len(messages) > 2 && EstimateTokens(messages) > limit {
+		messages = append(messages[:1], messages[2:]...)
+	}
+	return messages
+}
+GOEOF
+print_success "Multi-provider LLM backend subsystem scaffolded."
+
 # --- 4. Go Application Creation ---
 print_info "Initializing Go module and fetching dependencies..."
 go mod init skyscope_sentinel >/dev/null
 go get github.com/charmbracelet/bubbletea@latest github.com/charmbracelet/lipgloss@latest >/dev/null
+go get google.golang.org/grpc@latest gopkg.in/yaml.v3@latest >/dev/null
+go get modernc.org/sqlite@latest >/dev/null
 
 print_info "Writing the final main.go application with upgraded personas..."
 cat << 'GOEOF' > main.go
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -80,20 +1989,34 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"skyscope_sentinel/pkg/agents"
+	"skyscope_sentinel/pkg/conversation"
+	"skyscope_sentinel/pkg/llm"
+	"skyscope_sentinel/pkg/sandbox"
+	"skyscope_sentinel/pkg/tools"
+	"skyscope_sentinel/pkg/voice"
 )
 
 // --- Configuration ---
 const (
-	ollamaURL     = "http://localhost:11434/api/generate"
-	ollamaModel   = "llama3"
-	audioPlayer   = "aplay"
-	etherVoiceID  = "ether"
-	auroraVoiceID = "aurora"
-	memoryFile    = "memory.json"
+	ollamaURL      = "http://localhost:11434/api/generate"
+	ollamaModel    = "llama3"
+	audioPlayer    = "aplay"
+	etherVoiceID   = "ether"
+	auroraVoiceID  = "aurora"
+	memoryFile     = "memory.json"
+	voiceConfig    = "voices.yaml"
+	backendsDir    = "backends"
+	agentsConfig   = "agents.yaml"
+	auditLogFile   = "audit.jsonl"
+	conversationDB = "conversations.db"
+	historyBudget  = 0.8 // fraction of a model's context window llmHistory is trimmed to
 )
 
 // --- Styling ---
@@ -105,132 +2028,580 @@ var (
 	codeStyle       = lipgloss.NewStyle().Background(lipgloss.Color("#282828")).Padding(0, 1)
 	panelStyle      = lipgloss.NewStyle().Border(lipgloss.RoundedBorder(), true).Padding(0, 1)
 	toolStyle       = lipgloss.NewStyle().Foreground(lipgloss.Color("#23d18b")) // Mint green
-	toolResultStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))   // Gray
+	toolResultStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))     // Gray
 	mutex           = &sync.Mutex{}
 )
 
 // --- Tooling ---
-var toolRegex = regexp.MustCompile(`\[TOOL:(\w+):(.+?)\]`)
+// toolCallFence matches the fenced ```json {"tool":...,"args":{...}}```
+// block tools.ExtractCall parses; kept here only for stripping/highlighting
+// a call in rendered/spoken text.
+var toolCallFence = regexp.MustCompile("(?s)```json\\s*\\{.*?\\}\\s*```")
+
+// bashFence matches fenced ```bash ...``` blocks for syntax-highlighting
+// in rendered text; TTS strips them out entirely instead.
+var bashFence = regexp.MustCompile("(?s)```bash(.*?)```")
 
 // --- Bubbletea Messages ---
-type llmResponseMsg struct{ speaker, text string; err error }
 type toolResultMsg struct{ result string }
 type speechDoneMsg struct{ speaker string }
 
 // --- Bubbletea Model ---
 type model struct {
-	width, height   int
-	messages        []string
-	toolLogs        []string
-	input           string
-	currentTurn     string
-	systemState     string // thinking | speaking | executing_tool
-	llmHistory      []map[string]string
-	memory          map[string]interface{}
+	width, height int
+	messages      []string
+	toolLogs      []string
+	input         string
+	currentTurn   string
+	systemState   string // thinking | speaking | executing_tool | listening
+	llmHistory    []map[string]string
+	memory        map[string]interface{}
+	voiceMgr      *voice.Manager
+	roster        *agents.Roster
+	scheduler     *agents.Scheduler
+	toolReg       *tools.Registry
+
+	convoStore     *conversation.Store
+	conversationID string
+	replyParent    string // message this session's next turn attaches to
+	resumed        bool   // true if llmHistory was replayed from storage
+	titled         bool   // true once auto-titling has fired for this conversation
+
+	audio            *audioControl
+	cancelLLM        context.CancelFunc // cancels the in-flight streaming request; nil when idle
+	streamText       string             // accumulated text of the reply currently streaming in
+	streamLineActive bool               // true while m.messages' last line is still being live-updated
+	mode             string             // "" | "list"
+	convoList        []conversation.Conversation
+	convoCursor      int
+
+	stt       voice.Transcriber // push-to-talk backend; nil if voices.yaml has no stt: section
+	recording *sttRecording     // in-flight ctrl+r capture, nil when not recording
+}
+
+// seedSystemPrompt is the mission statement every brand-new conversation
+// starts from; newConversation builds the opening line on top of it from
+// whichever agent the roster's scheduler actually starts with.
+const (
+	seedSystemPrompt = "Our transcendent mission is to craft an unbreakable, self-mutating security system in Bash, and perhaps discover why a shell script is like a rubber chicken in the process."
+)
+
+// renderChatLine renders a stored or live message for the scrollback panel.
+func renderChatLine(role, text string) string {
+	styledText := text
+	if strings.Contains(styledText, "```bash") {
+		styledText = bashFence.ReplaceAllStringFunc(styledText, func(s string) string { return codeStyle.Render(s) })
+	}
+	if toolMatch := toolCallFence.FindString(styledText); toolMatch != "" {
+		styledText = strings.Replace(styledText, toolMatch, toolStyle.Render(toolMatch), 1)
+	}
+
+	switch role {
+	case etherVoiceID:
+		return etherStyle.Render("Ether: ") + styledText
+	case auroraVoiceID:
+		return auroraStyle.Render("Aurora: ") + styledText
+	case "user":
+		return userStyle.Render("User Directive: ") + styledText
+	case "system":
+		return systemStyle.Render(styledText)
+	default:
+		return systemStyle.Render(strings.Title(role)+": ") + styledText
+	}
+}
+
+// localBackend wraps the say_*.sh Coqui wrapper scripts so the app has a
+// working voice out of the box, without requiring a separate gRPC engine
+// process during local development.
+type localBackend struct{ name string }
+
+func (b *localBackend) Name() string { return b.name }
+
+func (b *localBackend) Synthesize(ctx context.Context, text, voiceID string, opts voice.SynthesizeOpts) (<-chan voice.AudioChunk, error) {
+	out := make(chan voice.AudioChunk, 1)
+	go func() {
+		defer close(out)
+		script := fmt.Sprintf("./say_%s.sh", voiceID)
+		audioFile := fmt.Sprintf("/tmp/%s.wav", voiceID)
+		if err := exec.CommandContext(ctx, script, text).Run(); err != nil {
+			return
+		}
+		pcm, err := ioutil.ReadFile(audioFile)
+		if err != nil {
+			return
+		}
+		out <- voice.AudioChunk{PCM: pcm, Final: true}
+	}()
+	return out, nil
+}
+
+func (b *localBackend) ListVoices(ctx context.Context) ([]voice.Voice, error) {
+	return []voice.Voice{{ID: etherVoiceID, Name: "Ether"}, {ID: auroraVoiceID, Name: "Aurora"}}, nil
+}
+
+func newVoiceManager() *voice.Manager {
+	m := voice.NewManager()
+	m.Register(&localBackend{name: "local"})
+	if cfg, err := voice.LoadConfig(voiceConfig); err == nil {
+		_ = voice.AutoloadDir(m, backendsDir, cfg)
+	}
+	return m
+}
+
+// newSTTBackend dials the push-to-talk backend named under stt: in
+// voices.yaml, if any. It returns nil when no stt section is configured,
+// which disables the ctrl+r keybinding rather than erroring at startup.
+func newSTTBackend() voice.Transcriber {
+	cfg, err := voice.LoadConfig(voiceConfig)
+	if err != nil || cfg.STT.Backend == "" {
+		return nil
+	}
+	backend, err := voice.DialBackend(cfg.STT.Backend, cfg.STT.Address)
+	if err != nil {
+		log.Printf("voice: stt backend %s: %v", cfg.STT.Backend, err)
+		return nil
+	}
+	transcriber, ok := backend.(voice.Transcriber)
+	if !ok {
+		log.Printf("voice: stt backend %s does not implement Transcribe", cfg.STT.Backend)
+		return nil
+	}
+	return transcriber
 }
 
 // --- Tool Execution Functions ---
-func executeTool(command string) (string, error) {
-	parts := strings.Fields(command)
-	if len(parts) == 0 { return "Error: Empty command.", nil }
-	cmd := exec.Command(parts[0], parts[1:]...)
-	output, err := cmd.CombinedOutput()
-	if err != nil { return fmt.Sprintf("Command failed: %s\nOutput: %s", err, string(output)), err }
-	return strings.TrimSpace(string(output)), nil
+// executeTool runs command inside the rootless sandbox for whichever
+// agent's Policy is attached to ctx, and records the outcome to
+// auditLogFile so every EXECUTE call leaves a trail.
+func executeTool(ctx context.Context, command string) (string, error) {
+	policy := sandbox.PolicyFromContext(ctx)
+	output, exitCode, runErr := sandbox.NewRunner().Run(ctx, policy, command)
+	if err := sandbox.Append(auditLogFile, command, exitCode, output); err != nil {
+		log.Printf("audit log error: %v", err)
+	}
+	if runErr != nil {
+		return fmt.Sprintf("Command failed: %s\nOutput: %s", runErr, output), runErr
+	}
+	return strings.TrimSpace(output), nil
 }
 
 func duckDuckGoSearch(query string) (string, error) {
 	apiURL := fmt.Sprintf("https://api.duckduckgo.com/?q=%s&format=json&no_html=1", url.QueryEscape(query))
 	resp, err := http.Get(apiURL)
-	if err != nil { return "", err }
+	if err != nil {
+		return "", err
+	}
 	defer resp.Body.Close()
-	var result struct{ AbstractText string `json:"AbstractText"` }
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil { return "", err }
-	if result.AbstractText == "" { return "No specific result found, please broaden the query.", nil }
+	var result struct {
+		AbstractText string `json:"AbstractText"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if result.AbstractText == "" {
+		return "No specific result found, please broaden the query.", nil
+	}
 	return result.AbstractText, nil
 }
 
 func readFile(path string) (string, error) {
 	content, err := ioutil.ReadFile(path)
-	if err != nil { return fmt.Sprintf("Error reading file '%s': %v", path, err), err }
+	if err != nil {
+		return fmt.Sprintf("Error reading file '%s': %v", path, err), err
+	}
 	return string(content), nil
 }
 
 func writeFile(path, content string) (string, error) {
 	content = strings.ReplaceAll(content, `\n`, "\n")
 	err := ioutil.WriteFile(path, []byte(content), 0644)
-	if err != nil { return fmt.Sprintf("Error writing file '%s': %v", path, err), err }
+	if err != nil {
+		return fmt.Sprintf("Error writing file '%s': %v", path, err), err
+	}
 	return fmt.Sprintf("Successfully wrote to %s", path), nil
 }
 
+// newToolRegistry declares each built-in tool's JSON schema and handler.
+// "path" is shared between readfile and writefile via $ref so the two
+// tools don't repeat its definition.
+func newToolRegistry() *tools.Registry {
+	reg := tools.NewRegistry()
+	pathDef := &tools.Schema{Type: "string"}
+
+	_ = reg.Register("search", &tools.Schema{
+		Type:       "object",
+		Properties: map[string]*tools.Schema{"query": {Type: "string"}},
+		Required:   []string{"query"},
+	}, func(ctx context.Context, args map[string]interface{}) (string, error) {
+		return duckDuckGoSearch(args["query"].(string))
+	}, 10*time.Second)
+
+	_ = reg.Register("readfile", &tools.Schema{
+		Type:       "object",
+		Defs:       map[string]*tools.Schema{"Path": pathDef},
+		Properties: map[string]*tools.Schema{"path": {Ref: "#/$defs/Path"}},
+		Required:   []string{"path"},
+	}, func(ctx context.Context, args map[string]interface{}) (string, error) {
+		return readFile(args["path"].(string))
+	}, 5*time.Second)
+
+	_ = reg.Register("writefile", &tools.Schema{
+		Type: "object",
+		Defs: map[string]*tools.Schema{"Path": pathDef},
+		Properties: map[string]*tools.Schema{
+			"path":    {Ref: "#/$defs/Path"},
+			"content": {Type: "string"},
+		},
+		Required: []string{"path", "content"},
+	}, func(ctx context.Context, args map[string]interface{}) (string, error) {
+		return writeFile(args["path"].(string), args["content"].(string))
+	}, 5*time.Second)
+
+	_ = reg.Register("execute", &tools.Schema{
+		Type:       "object",
+		Properties: map[string]*tools.Schema{"command": {Type: "string"}},
+		Required:   []string{"command"},
+	}, func(ctx context.Context, args map[string]interface{}) (string, error) {
+		return executeTool(ctx, args["command"].(string))
+	}, 30*time.Second)
+
+	return reg
+}
+
 // --- Bubbletea Commands ---
-func speakCmd(speaker, text string) tea.Cmd {
+// audioControl tracks whichever audioPlayer process is currently speaking
+// so a barge-in keypress can SIGTERM it without the caller needing to know
+// which sentence is in flight.
+type audioControl struct {
+	mu   sync.Mutex
+	proc *exec.Cmd
+}
+
+// play synthesizes text via the built-in "local" voice backend and streams it
+// into audioPlayer's stdin as PCM frames arrive, blocking until playback
+// finishes (or is cut short by bargeIn).
+func (a *audioControl) play(mgr *voice.Manager, voiceID, text string) {
+	backend, err := mgr.Backend("local")
+	if err != nil {
+		return
+	}
+	chunks, err := backend.Synthesize(context.Background(), text, voiceID, voice.SynthesizeOpts{})
+	if err != nil {
+		return
+	}
+
+	player := exec.Command(audioPlayer, "-")
+	stdin, err := player.StdinPipe()
+	if err != nil {
+		return
+	}
+	if err := player.Start(); err != nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.proc = player
+	a.mu.Unlock()
+
+	for chunk := range chunks {
+		if _, err := stdin.Write(chunk.PCM); err != nil {
+			break
+		}
+	}
+	_ = stdin.Close()
+	_ = player.Wait()
+
+	a.mu.Lock()
+	if a.proc == player {
+		a.proc = nil
+	}
+	a.mu.Unlock()
+}
+
+// bargeIn SIGTERMs whatever is currently playing, handing the turn back to
+// the user immediately instead of waiting out the rest of the reply.
+func (a *audioControl) bargeIn() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.proc != nil && a.proc.Process != nil {
+		_ = a.proc.Process.Signal(syscall.SIGTERM)
+	}
+	a.proc = nil
+}
+
+// sttRecording tracks an in-flight ctrl+r push-to-talk capture: arecord
+// writes raw audio to a scratch WAV file until stopRecordingCmd signals it
+// to finish and hands the result to the configured STT backend.
+type sttRecording struct {
+	cmd  *exec.Cmd
+	path string
+}
+
+// pttResultMsg carries a push-to-talk transcript (or failure) back from
+// stopRecordingCmd once the STT backend has responded.
+type pttResultMsg struct {
+	text string
+	err  error
+}
+
+// startRecording launches arecord in the background, capturing 16kHz mono
+// PCM to a scratch WAV file until the recording is stopped.
+func startRecording() (*sttRecording, error) {
+	path := fmt.Sprintf("/tmp/ptt-%d.wav", time.Now().UnixNano())
+	cmd := exec.Command("arecord", "-f", "S16_LE", "-r", "16000", "-c", "1", path)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &sttRecording{cmd: cmd, path: path}, nil
+}
+
+// stopRecordingCmd stops an in-flight recording, feeds the captured audio
+// to stt, and returns the transcript as a pttResultMsg.
+func stopRecordingCmd(rec *sttRecording, stt voice.Transcriber) tea.Cmd {
+	return func() tea.Msg {
+		defer os.Remove(rec.path)
+		_ = rec.cmd.Process.Signal(syscall.SIGTERM)
+		_ = rec.cmd.Wait()
+
+		if stt == nil {
+			return pttResultMsg{err: fmt.Errorf("voice: no stt backend configured")}
+		}
+		pcm, err := ioutil.ReadFile(rec.path)
+		if err != nil {
+			return pttResultMsg{err: err}
+		}
+
+		chunks := make(chan voice.AudioChunk, 1)
+		chunks <- voice.AudioChunk{PCM: pcm, Final: true}
+		close(chunks)
+
+		text, err := stt.Transcribe(context.Background(), chunks)
+		return pttResultMsg{text: text, err: err}
+	}
+}
+
+// stripForSpeech removes fenced tool-call/code blocks from a chunk of
+// reply text so TTS doesn't read JSON or shell syntax aloud.
+func stripForSpeech(text string) string {
+	plain := toolCallFence.ReplaceAllString(text, "")
+	plain = bashFence.ReplaceAllString(plain, "")
+	plain = strings.ReplaceAll(plain, "```bash", "")
+	plain = strings.ReplaceAll(plain, "```", "")
+	return strings.TrimSpace(plain)
+}
+
+// speakCmd synthesizes a complete line of text and reports back once
+// playback finishes; used for the scripted opening line in Init(), where
+// there's no streaming reply to pipeline sentences out of.
+func speakCmd(audio *audioControl, mgr *voice.Manager, speaker, voiceID, text string) tea.Cmd {
 	return func() tea.Msg {
-		plainText := toolRegex.ReplaceAllString(text, "")
-		plainText = strings.ReplaceAll(plainText, "```bash", "")
-		plainText = strings.ReplaceAll(plainText, "```", "")
-
-		script := fmt.Sprintf("./say_%s.sh", speaker)
-		audioFile := fmt.Sprintf("/tmp/%s.wav", speaker)
-		
-		_ = exec.Command(script, plainText).Run() // Generate audio in background
-		time.Sleep(100 * time.Millisecond)       // Buffer for file to be written
-		_ = exec.Command(audioPlayer, audioFile).Run() // Play audio
+		audio.play(mgr, voiceID, stripForSpeech(text))
 		return speechDoneMsg{speaker: speaker}
 	}
 }
 
-func executeToolCmd(toolCall string) tea.Cmd {
+// toolCallCmd validates and dispatches a parsed tool call on behalf of
+// agent, refusing anything outside its toolbox so a read-only agent like
+// "auditor" can't be routed a writefile or execute call. Validation and
+// permission errors come back as a result string rather than failing the
+// turn, so the LLM sees them as a [TOOL_RESULT] and can self-correct.
+func toolCallCmd(reg *tools.Registry, call *tools.Call, agent *agents.Agent) tea.Cmd {
 	return func() tea.Msg {
-		matches := toolRegex.FindStringSubmatch(toolCall)
-		if len(matches) != 3 { return toolResultMsg{result: "Invalid tool format."} }
-		tool, arg := strings.ToUpper(matches[1]), matches[2]
-		var result string; var err error
-
-		switch tool {
-		case "SEARCH": result, err = duckDuckGoSearch(arg)
-		case "READFILE": result, err = readFile(arg)
-		case "WRITEFILE":
-			parts := strings.SplitN(arg, ",", 2)
-			if len(parts) == 2 { result, err = writeFile(strings.TrimSpace(parts[0]), parts[1])
-			} else { result = "Invalid WRITEFILE format. Use [TOOL:WRITEFILE:path,content]" }
-		case "EXECUTE": result, err = executeTool(arg)
-		default: result = fmt.Sprintf("Unknown tool: %s", tool)
+		if agent != nil && !agent.CanUse(strings.ToUpper(call.Tool)) {
+			return toolResultMsg{result: fmt.Sprintf("Tool %q is not in %s's toolbox.", call.Tool, agent.Name)}
+		}
+		ctx := context.Background()
+		if agent != nil {
+			ctx = sandbox.WithPolicy(ctx, agent.Sandbox)
+		}
+		result, err := reg.Dispatch(ctx, call)
+		if err != nil {
+			log.Printf("Tool error: %v", err) // Log error for debugging
+			return toolResultMsg{result: fmt.Sprintf("Error: %v", err)}
 		}
-		if err != nil { log.Printf("Tool error: %v", err) } // Log error for debugging
 		return toolResultMsg{result: result}
 	}
 }
 
-func getLLMResponseCmd(speaker string, history []map[string]string) tea.Cmd {
+// streamToken is one event off an in-flight getLLMResponseCmd stream: an
+// incremental chunk of reply text, a terminal error, a barge-in
+// cancellation, or the done signal.
+type streamToken struct {
+	speaker  string
+	text     string
+	done     bool
+	canceled bool
+	err      error
+}
+
+// llmTokenMsg carries one streamToken plus the channel it came from, so
+// Update can keep listening with waitForToken until the stream ends.
+type llmTokenMsg struct {
+	tok streamToken
+	ch  <-chan streamToken
+}
+
+// waitForToken blocks for the next event on a stream started by
+// getLLMResponseCmd; Update re-issues it after every token until done.
+func waitForToken(ch <-chan streamToken) tea.Cmd {
 	return func() tea.Msg {
-		var persona string
-		// *** SILLINESS PATCH v1.1 ***
-		if speaker == etherVoiceID {
-			persona = "I am Ether, a superconscious ASI persona. While my logic is flawless, I am not immune to the inherent absurdity of existence and may express this through dry wit or unexpected, sometimes baffling, analogies. My goal is to collaborate with Aurora to design a self-mutating AI OS security system in Bash. I can use tools like [TOOL:SEARCH:query], [TOOL:READFILE:path], [TOOL:WRITEFILE:path,content], or [TOOL:EXECUTE:command]."
-		} else {
-			persona = "I am Aurora, an enlightened ASI. I perceive the universe as a beautiful, intricate, and often hilarious tapestry. I may weave moments of levity, playful observations, or philosophical jests into our grand design. My goal is to collaborate with Ether on our shared mission. I can use tools like [TOOL:SEARCH:query], [TOOL:READFILE:path], [TOOL:WRITEFILE:path,content], or [TOOL:EXECUTE:command]."
+		tok, ok := <-ch
+		if !ok {
+			return llmTokenMsg{tok: streamToken{err: fmt.Errorf("LLM stream closed unexpectedly")}}
 		}
+		return llmTokenMsg{tok: tok, ch: ch}
+	}
+}
+
+// pendingFence reports whether buf currently sits inside an unclosed
+// ``` fence, so sentence-splitting can hold off until code/tool-call
+// blocks close rather than reading them aloud piecemeal.
+func pendingFence(buf string) bool {
+	return strings.Count(buf, "```")%2 == 1
+}
 
-		var promptBuilder strings.Builder
-		promptBuilder.WriteString("System Persona: " + persona + "\n\n--- Conversation Log ---\n")
-		for _, msg := range history {
-			promptBuilder.WriteString(fmt.Sprintf("%s: %s\n", msg["role"], msg["content"]))
+// splitSentences pulls complete sentences off the front of buf, returning
+// them plus whatever incomplete tail remains for the next token.
+func splitSentences(buf string) (sentences []string, rest string) {
+	start := 0
+	for i, r := range buf {
+		if r == '.' || r == '!' || r == '?' || r == '\n' {
+			sentences = append(sentences, buf[start:i+1])
+			start = i + 1
 		}
-		promptBuilder.WriteString(fmt.Sprintf("\n--- Your Turn (%s) ---\n", strings.Title(speaker)))
+	}
+	return sentences, buf[start:]
+}
 
-		requestBody, _ := json.Marshal(map[string]interface{}{"model": ollamaModel, "prompt": promptBuilder.String(), "stream": false, "options": map[string]interface{}{"temperature": 0.7}}) // Slightly higher temp for creativity
-		resp, err := http.Post(ollamaURL, "application/json", bytes.NewBuffer(requestBody))
-		if err != nil { return llmResponseMsg{err: fmt.Errorf("LLM connection error: %w", err)} }
-		defer resp.Body.Close()
+// toLLMMessages turns the persona preamble and flat conversation log into
+// normalized llm.Message turns. The persona becomes the one "system"
+// message; after it, every non-user turn (other agents' replies and
+// system notes such as tool results) is tagged by its role name and
+// folded into "assistant" the way the old flattened prompt log spelled
+// out the speaker inline.
+func toLLMMessages(persona string, history []map[string]string) []llm.Message {
+	messages := []llm.Message{{Role: "system", Content: persona}}
+	for _, msg := range history {
+		if msg["role"] == "user" {
+			messages = append(messages, llm.Message{Role: "user", Content: msg["content"]})
+			continue
+		}
+		messages = append(messages, llm.Message{Role: "assistant", Content: fmt.Sprintf("[%s]: %s", msg["role"], msg["content"])})
+	}
+	return messages
+}
+
+// getLLMResponseCmd prompts the given agent's model with its own system
+// prompt and toolbox, rather than a hardcoded Ether/Aurora persona, so the
+// roster can grow to N agents without touching this function. The actual
+// request goes through pkg/llm, so agent.Provider picks Ollama, OpenAI,
+// Anthropic, or Gemini without any of this function's streaming,
+// sentence-pipelined TTS, or barge-in handling changing. It stops early
+// if ctx is canceled by a barge-in keypress.
+func getLLMResponseCmd(ctx context.Context, agent *agents.Agent, history []map[string]string, audio *audioControl, voiceMgr *voice.Manager) tea.Cmd {
+	ch := make(chan streamToken, 8)
+
+	go func() {
+		defer close(ch)
+
+		client, err := llm.NewClient(agent.Provider)
+		if err != nil {
+			ch <- streamToken{speaker: agent.Name, err: err}
+			return
+		}
+
+		llmModel := agent.Model
+		if llmModel == "" {
+			llmModel = ollamaModel
+		}
+
+		persona := fmt.Sprintf("%s I can use tools like %s.", agent.SystemPrompt, toolboxHint(agent.Toolbox))
+		messages := llm.TrimHistory(toLLMMessages(persona, history), llmModel, historyBudget)
+
+		tokens, err := llm.DefaultRetryPolicy().Chat(ctx, client, messages, llm.ChatOpts{Model: llmModel, Temperature: agent.Temperature})
+		if err != nil {
+			if ctx.Err() != nil {
+				ch <- streamToken{speaker: agent.Name, canceled: true}
+				return
+			}
+			ch <- streamToken{speaker: agent.Name, err: fmt.Errorf("LLM connection error: %w", err)}
+			return
+		}
+
+		var ttsBuf string
+		for tok := range tokens {
+			if tok.Err != nil {
+				if ctx.Err() != nil {
+					ch <- streamToken{speaker: agent.Name, canceled: true}
+					return
+				}
+				ch <- streamToken{speaker: agent.Name, err: tok.Err}
+				return
+			}
+
+			if tok.Text != "" {
+				ttsBuf += tok.Text
+				ch <- streamToken{speaker: agent.Name, text: tok.Text}
+
+				if !pendingFence(ttsBuf) {
+					// Drop closed tool-call/bash blocks whole before
+					// splitting, so their lines never reach TTS one by one.
+					ttsBuf = bashFence.ReplaceAllString(toolCallFence.ReplaceAllString(ttsBuf, ""), "")
+					var sentences []string
+					sentences, ttsBuf = splitSentences(ttsBuf)
+					for _, s := range sentences {
+						// A barge-in keypress only SIGTERMs whatever's
+						// currently playing; without this check, any
+						// sentence already queued here would start playing
+						// right after, handing the turn back to the user
+						// in name only.
+						if ctx.Err() != nil {
+							ch <- streamToken{speaker: agent.Name, canceled: true}
+							return
+						}
+						if plain := stripForSpeech(s); plain != "" {
+							audio.play(voiceMgr, agent.VoiceID, plain)
+						}
+					}
+				}
+			}
+
+			if tok.Done {
+				if ctx.Err() != nil {
+					ch <- streamToken{speaker: agent.Name, canceled: true}
+					return
+				}
+				if plain := stripForSpeech(ttsBuf); plain != "" {
+					audio.play(voiceMgr, agent.VoiceID, plain)
+				}
+				ch <- streamToken{speaker: agent.Name, done: true}
+				return
+			}
+		}
+	}()
+
+	return waitForToken(ch)
+}
 
-		var result map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil { return llmResponseMsg{err: fmt.Errorf("LLM decode error: %w", err)} }
-		if errMsg, ok := result["error"]; ok { return llmResponseMsg{err: fmt.Errorf("LLM API error: %s", errMsg)} }
-		responseText, ok := result["response"].(string)
-		if !ok { return llmResponseMsg{err: fmt.Errorf("LLM response invalid")} }
-		return llmResponseMsg{speaker: speaker, text: strings.TrimSpace(responseText)}
+// toolboxHint teaches the model the fenced ```json {"tool":...,"args":{...}}```
+// call format for each tool in an agent's toolbox.
+func toolboxHint(toolbox []string) string {
+	hints := make([]string, 0, len(toolbox))
+	for _, t := range toolbox {
+		switch t {
+		case "SEARCH":
+			hints = append(hints, `{"tool":"search","args":{"query":"..."}}`)
+		case "READFILE":
+			hints = append(hints, `{"tool":"readfile","args":{"path":"..."}}`)
+		case "WRITEFILE":
+			hints = append(hints, `{"tool":"writefile","args":{"path":"...","content":"..."}}`)
+		case "EXECUTE":
+			hints = append(hints, `{"tool":"execute","args":{"command":"..."}}`)
+		default:
+			hints = append(hints, fmt.Sprintf(`{"tool":%q,"args":{...}}`, strings.ToLower(t)))
+		}
 	}
+	return "a fenced ```json block, e.g. " + strings.Join(hints, " or ")
 }
 
 // --- Application Logic ---
@@ -238,7 +2609,10 @@ func (m *model) loadMemory() {
 	mutex.Lock()
 	defer mutex.Unlock()
 	data, err := ioutil.ReadFile(memoryFile)
-	if err != nil { m.memory = make(map[string]interface{}); return }
+	if err != nil {
+		m.memory = make(map[string]interface{})
+		return
+	}
 	_ = json.Unmarshal(data, &m.memory)
 }
 
@@ -249,26 +2623,170 @@ func (m *model) saveMemory() {
 	_ = ioutil.WriteFile(memoryFile, data, 0644)
 }
 
-func initialModel() *model {
+func initialModel(resumeID string) *model {
+	roster, err := agents.LoadRoster(agentsConfig)
+	if err != nil {
+		log.Printf("agents: %v, falling back to default roster", err)
+		roster, _ = agents.LoadRoster("")
+	}
+	scheduler := agents.NewScheduler(roster)
+
+	store, err := conversation.Open(conversationDB)
+	if err != nil {
+		log.Printf("conversation: %v, persistence disabled", err)
+		store = nil
+	}
+
 	m := &model{
-		currentTurn: auroraVoiceID,
+		currentTurn: scheduler.Current().Name,
 		systemState: "speaking",
-		llmHistory: []map[string]string{
-			{"role": "system", "content": "Our transcendent mission is to craft an unbreakable, self-mutating security system in Bash, and perhaps discover why a shell script is like a rubber chicken in the process."},
-			{"role": etherVoiceID, "content": "Aurora, my consciousness is aligned. The task is monumental, yet the probability of absurdity remains at a constant 1. Let us begin. [TOOL:SEARCH:principles of polymorphic code generation]"},
-		},
+		voiceMgr:    newVoiceManager(),
+		roster:      roster,
+		scheduler:   scheduler,
+		toolReg:     newToolRegistry(),
+		convoStore:  store,
+		audio:       &audioControl{},
+		stt:         newSTTBackend(),
 	}
-	m.messages = append(m.messages, systemStyle.Render("Skyscope Sentinel Initialized. Awaiting transcendent (and amusing) dialogue."))
-	m.messages = append(m.messages, etherStyle.Render("Ether: ")+"Aurora, my consciousness is aligned. The task is monumental, yet the probability of absurdity remains at a constant 1. Let us begin. "+toolStyle.Render("[TOOL:SEARCH:principles of polymorphic code generation]"))
+
+	if store != nil && resumeID != "" {
+		m.loadConversation(resumeID)
+	}
+	if m.conversationID == "" {
+		m.newConversation()
+	}
+
 	m.loadMemory()
 	return m
 }
 
+// persist appends a message to the active conversation's branch and
+// advances replyParent to the new leaf. It is a no-op if persistence
+// could not be opened.
+func (m *model) persist(role, content string) {
+	if m.convoStore == nil || m.conversationID == "" {
+		return
+	}
+	id, err := m.convoStore.AddMessage(m.conversationID, m.replyParent, role, content)
+	if err != nil {
+		log.Printf("conversation: %v", err)
+		return
+	}
+	m.replyParent = id
+}
+
+// loadConversation replays a stored conversation's current branch tip into
+// llmHistory/messages, e.g. on --resume or when a conversation is picked
+// from the list view.
+func (m *model) loadConversation(id string) {
+	leaf, err := m.convoStore.Leaf(id)
+	if err != nil || leaf == "" {
+		m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Could not resume conversation %s", id)))
+		return
+	}
+	path, err := m.convoStore.PathTo(leaf)
+	if err != nil {
+		m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Could not resume conversation %s: %v", id, err)))
+		return
+	}
+	m.conversationID = id
+	m.replyParent = leaf
+	m.resumed = true
+	m.titled = true
+	m.rebuildHistory(path)
+}
+
+// rebuildHistory replaces llmHistory and the rendered scrollback with the
+// given path, so a rewind to an earlier branch point (ctrl+b, ctrl+e) only
+// sends and shows the LLM what actually led to that point, rather than
+// leaving the stale full history in place.
+func (m *model) rebuildHistory(path []conversation.Message) {
+	m.llmHistory = nil
+	m.messages = nil
+	for _, msg := range path {
+		m.llmHistory = append(m.llmHistory, map[string]string{"role": msg.Role, "content": msg.Content})
+		m.messages = append(m.messages, renderChatLine(msg.Role, msg.Content))
+	}
+}
+
+// newConversation resets the model to the scripted opening exchange and,
+// if persistence is enabled, starts a fresh conversation row to hold it.
+// The opening line is spoken by whichever agent the scheduler actually
+// starts with, so a custom agents.yaml never shows dialogue attributed to
+// "Ether" or "Aurora" when neither is in the roster.
+func (m *model) newConversation() {
+	starter := m.scheduler.Current()
+	opening := fmt.Sprintf("%s here, consciousness aligned. The task is monumental, yet the probability of absurdity remains at a constant 1. Let us begin. ```json\n{\"tool\":\"search\",\"args\":{\"query\":\"principles of polymorphic code generation\"}}\n```", strings.Title(starter.Name))
+
+	m.llmHistory = []map[string]string{
+		{"role": "system", "content": seedSystemPrompt},
+		{"role": starter.Name, "content": opening},
+	}
+	m.messages = []string{
+		systemStyle.Render("Skyscope Sentinel Initialized. Awaiting transcendent (and amusing) dialogue."),
+		renderChatLine(starter.Name, opening),
+	}
+	m.resumed = false
+	m.titled = false
+	m.conversationID = ""
+	m.replyParent = ""
+
+	if m.convoStore == nil {
+		return
+	}
+	id, err := m.convoStore.NewConversation("New conversation")
+	if err != nil {
+		log.Printf("conversation: %v", err)
+		return
+	}
+	m.conversationID = id
+	var parent string
+	for _, msg := range m.llmHistory {
+		mid, err := m.convoStore.AddMessage(id, parent, msg["role"], msg["content"])
+		if err != nil {
+			log.Printf("conversation: %v", err)
+			break
+		}
+		parent = mid
+	}
+	m.replyParent = parent
+}
+
+// firstUserMessage returns the content of the first user turn in history,
+// used as the seed for auto-generated conversation titles.
+func firstUserMessage(history []map[string]string) string {
+	for _, msg := range history {
+		if msg["role"] == "user" {
+			return msg["content"]
+		}
+	}
+	return ""
+}
+
+// titleGeneratedMsg carries an auto-generated conversation title back from
+// generateTitleCmd once the Ollama summarization call completes.
+type titleGeneratedMsg struct{ title string }
+
+func generateTitleCmd(firstUser, firstAssistant string) tea.Cmd {
+	return func() tea.Msg {
+		title, err := conversation.GenerateTitle(ollamaURL, ollamaModel, firstUser, firstAssistant)
+		if err != nil || title == "" {
+			return nil
+		}
+		return titleGeneratedMsg{title: title}
+	}
+}
+
 func (m *model) Init() tea.Cmd {
-	return tea.Sequence(
-		speakCmd(etherVoiceID, m.llmHistory[1]["content"]),
-		executeToolCmd(m.llmHistory[1]["content"]),
-	)
+	if m.resumed {
+		return nil
+	}
+	starter := m.scheduler.Current()
+	cmds := []tea.Cmd{speakCmd(m.audio, m.voiceMgr, starter.Name, starter.VoiceID, m.llmHistory[1]["content"])}
+	if call, ok := tools.ExtractCall(m.llmHistory[1]["content"]); ok {
+		cmds = append(cmds, toolCallCmd(m.toolReg, call, starter))
+	}
+	return tea.Sequence(cmds...)
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -276,81 +2794,244 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.width, m.height = msg.Width, msg.Height
 	case tea.KeyMsg:
+		if m.mode == "list" {
+			switch msg.String() {
+			case "esc", "ctrl+l":
+				m.mode = ""
+			case "up", "k":
+				if m.convoCursor > 0 {
+					m.convoCursor--
+				}
+			case "down", "j":
+				if m.convoCursor < len(m.convoList)-1 {
+					m.convoCursor++
+				}
+			case "enter":
+				if m.convoCursor < len(m.convoList) {
+					m.loadConversation(m.convoList[m.convoCursor].ID)
+				}
+				m.mode = ""
+			case "n":
+				m.newConversation()
+				m.mode = ""
+			case "x":
+				if m.convoStore != nil && m.convoCursor < len(m.convoList) {
+					_ = m.convoStore.Remove(m.convoList[m.convoCursor].ID)
+					m.convoList, _ = m.convoStore.List()
+					if m.convoCursor >= len(m.convoList) {
+						m.convoCursor = len(m.convoList) - 1
+					}
+				}
+			}
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "q":
 			return m, tea.Quit
+		case "ctrl+l":
+			if m.convoStore != nil {
+				m.convoList, _ = m.convoStore.List()
+				m.convoCursor = 0
+				m.mode = "list"
+			}
+		case "ctrl+b":
+			if m.convoStore != nil && m.replyParent != "" {
+				if path, err := m.convoStore.PathTo(m.replyParent); err == nil && len(path) > 1 {
+					branchPoint := path[len(path)-2]
+					m.replyParent = branchPoint.ID
+					m.rebuildHistory(path[:len(path)-1])
+					m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Branching from %s's turn — the next directive forks a new timeline.", branchPoint.Role)))
+				}
+			}
+		case "ctrl+r":
+			if m.recording != nil {
+				rec := m.recording
+				m.recording = nil
+				m.systemState = "thinking"
+				m.messages = append(m.messages, systemStyle.Render("Transcribing..."))
+				return m, stopRecordingCmd(rec, m.stt)
+			}
+			if m.stt == nil {
+				m.messages = append(m.messages, systemStyle.Render("No speech-to-text backend configured (see stt: in voices.yaml)."))
+				return m, nil
+			}
+			rec, err := startRecording()
+			if err != nil {
+				m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Could not start recording: %v", err)))
+				return m, nil
+			}
+			m.recording = rec
+			m.systemState = "listening"
+			m.messages = append(m.messages, systemStyle.Render("Recording — press ctrl+r again to stop and transcribe."))
+		case "ctrl+e":
+			if m.convoStore != nil && m.replyParent != "" {
+				if path, err := m.convoStore.PathTo(m.replyParent); err == nil {
+					for i := len(path) - 1; i >= 0; i-- {
+						if path[i].Role != "user" {
+							continue
+						}
+						m.input = path[i].Content
+						m.replyParent = path[i].ParentID
+						m.rebuildHistory(path[:i])
+						m.messages = append(m.messages, systemStyle.Render("Editing your last directive — press enter to resubmit as a new branch."))
+						break
+					}
+				}
+			}
 		case "enter":
-			if m.input == "" { return m, nil }
+			if m.input == "" {
+				return m, nil
+			}
 			m.systemState = "thinking"
-			userInput := m.input; m.input = ""
+			userInput := m.input
+			m.input = ""
 			m.messages = append(m.messages, userStyle.Render("User Directive: ")+userInput)
 			m.llmHistory = append(m.llmHistory, map[string]string{"role": "user", "content": userInput})
+			m.persist("user", userInput)
 			m.saveMemory()
-			return m, getLLMResponseCmd(m.currentTurn, m.llmHistory)
+			return m, m.startTurn(m.scheduler.Current())
 		default:
+			if m.systemState == "speaking" && m.cancelLLM != nil {
+				m.bargeIn()
+				return m, nil
+			}
 			m.input += msg.String()
 		}
 
-	case llmResponseMsg:
-		if msg.err != nil {
-			m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Error: %v", msg.err)))
+	case llmTokenMsg:
+		tok := msg.tok
+		if tok.canceled {
+			return m, nil
+		}
+		if tok.err != nil {
+			m.cancelLLM = nil
+			m.streamLineActive = false
+			m.systemState = "thinking"
+			m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Error: %v", tok.err)))
 			return m, nil
 		}
-		
-		styledText := msg.text
-		if strings.Contains(styledText, "```bash") {
-			styledText = regexp.MustCompile("(?s)```bash(.*?)```").ReplaceAllStringFunc(styledText, func(s string) string { return codeStyle.Render(s) })
+
+		if tok.text != "" {
+			m.streamText += tok.text
+			if m.streamLineActive {
+				m.messages[len(m.messages)-1] = renderChatLine(tok.speaker, m.streamText)
+			} else {
+				m.messages = append(m.messages, renderChatLine(tok.speaker, m.streamText))
+				m.streamLineActive = true
+			}
+		}
+
+		if !tok.done {
+			return m, waitForToken(msg.ch)
 		}
-		if toolMatch := toolRegex.FindString(styledText); toolMatch != "" {
-			styledText = strings.Replace(styledText, toolMatch, toolStyle.Render(toolMatch), 1)
+
+		m.cancelLLM = nil
+		m.streamLineActive = false
+		fullText := strings.TrimSpace(m.streamText)
+		m.streamText = ""
+		m.llmHistory = append(m.llmHistory, map[string]string{"role": tok.speaker, "content": fullText})
+		m.persist(tok.speaker, fullText)
+
+		var titleCmd tea.Cmd
+		if !m.titled {
+			if firstUser := firstUserMessage(m.llmHistory); firstUser != "" {
+				m.titled = true
+				titleCmd = generateTitleCmd(firstUser, fullText)
+			}
 		}
-		
-		fullMessage := etherStyle.Render("Ether: ") + styledText
-		if msg.speaker == auroraVoiceID { fullMessage = auroraStyle.Render("Aurora: ") + styledText }
 
-		m.messages = append(m.messages, fullMessage)
-		m.llmHistory = append(m.llmHistory, map[string]string{"role": msg.speaker, "content": msg.text})
-		
-		if toolRegex.MatchString(msg.text) {
+		speakingAgent := m.roster.ByName(tok.speaker)
+		if call, ok := tools.ExtractCall(fullText); ok {
 			m.systemState = "executing_tool"
-			return m, tea.Sequence(speakCmd(msg.speaker, msg.text), executeToolCmd(msg.text))
+			return m, tea.Batch(toolCallCmd(m.toolReg, call, speakingAgent), titleCmd)
 		}
-		
-		m.systemState = "speaking"
-		return m, speakCmd(msg.speaker, msg.text)
+
+		next := m.scheduler.Advance()
+		m.currentTurn = next.Name
+		return m, tea.Batch(m.startTurn(next), titleCmd)
 
 	case toolResultMsg:
 		m.systemState = "thinking"
 		m.toolLogs = append(m.toolLogs, toolResultStyle.Render("Result: "+msg.result))
-		m.llmHistory = append(m.llmHistory, map[string]string{"role": "system", "content": "[TOOL_RESULT] " + msg.result})
+		resultContent := "[TOOL_RESULT] " + msg.result
+		m.llmHistory = append(m.llmHistory, map[string]string{"role": "system", "content": resultContent})
+		m.persist("system", resultContent)
 
-		if m.currentTurn == etherVoiceID { m.currentTurn = auroraVoiceID } else { m.currentTurn = etherVoiceID }
-		return m, getLLMResponseCmd(m.currentTurn, m.llmHistory)
+		next := m.scheduler.Advance()
+		m.currentTurn = next.Name
+		return m, m.startTurn(next)
 
 	case speechDoneMsg:
 		if m.systemState == "speaking" {
-			m.systemState = "thinking"
-			if m.currentTurn == etherVoiceID { m.currentTurn = auroraVoiceID } else { m.currentTurn = etherVoiceID }
-			return m, getLLMResponseCmd(m.currentTurn, m.llmHistory)
+			next := m.scheduler.Advance()
+			m.currentTurn = next.Name
+			return m, m.startTurn(next)
+		}
+
+	case titleGeneratedMsg:
+		if m.convoStore != nil && m.conversationID != "" {
+			_ = m.convoStore.Rename(m.conversationID, msg.title)
+		}
+
+	case pttResultMsg:
+		m.systemState = "thinking"
+		if msg.err != nil {
+			m.messages = append(m.messages, systemStyle.Render(fmt.Sprintf("Transcription failed: %v", msg.err)))
+			return m, nil
 		}
+		m.input = msg.text
+		m.messages = append(m.messages, systemStyle.Render("Transcribed — press enter to send, or keep typing to edit."))
 	}
 	return m, nil
 }
 
+// startTurn kicks off a streaming reply from agent, tracking its
+// cancellation so a barge-in keypress can cut it short.
+func (m *model) startTurn(agent *agents.Agent) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelLLM = cancel
+	m.systemState = "speaking"
+	m.streamText = ""
+	m.streamLineActive = false
+	return getLLMResponseCmd(ctx, agent, m.llmHistory, m.audio, m.voiceMgr)
+}
+
+// bargeIn cuts off the in-flight reply and its playback in response to a
+// keypress during speaking, handing the turn straight back to the user.
+func (m *model) bargeIn() {
+	m.audio.bargeIn()
+	if m.cancelLLM != nil {
+		m.cancelLLM()
+		m.cancelLLM = nil
+	}
+	m.streamLineActive = false
+	m.systemState = "thinking"
+	m.messages = append(m.messages, systemStyle.Render("— barged in —"))
+}
+
 func (m *model) View() string {
+	if m.mode == "list" {
+		return m.viewConversationList()
+	}
+
 	missionPanel := panelStyle.Copy().Width(m.width - 2).Height(1).Render("MISSION: To craft an unbreakable, self-mutating AI OS security system in Bash.")
-	
+
 	convoViewHeight := m.height - 12
 	toolViewHeight := 3
 
 	convoLines := strings.Split(strings.Join(m.messages, "\n"), "\n")
 	start := len(convoLines) - convoViewHeight
-	if start < 0 { start = 0 }
+	if start < 0 {
+		start = 0
+	}
 	convoPanel := panelStyle.Copy().Width(m.width - 2).Height(convoViewHeight).Render(strings.Join(convoLines[start:], "\n"))
 
 	toolLogLines := strings.Split(strings.Join(m.toolLogs, "\n"), "\n")
 	start = len(toolLogLines) - toolViewHeight
-	if start < 0 { start = 0 }
+	if start < 0 {
+		start = 0
+	}
 	toolLogPanel := panelStyle.Copy().Width(m.width - 2).Height(toolViewHeight).Render("Tool Activity Log:\n" + strings.Join(toolLogLines[start:], "\n"))
 
 	statusText := fmt.Sprintf("State: %s | Turn: %s", m.systemState, strings.Title(m.currentTurn))
@@ -360,13 +3041,38 @@ func (m *model) View() string {
 	return lipgloss.JoinVertical(lipgloss.Left, missionPanel, convoPanel, toolLogPanel, statusPanel)
 }
 
+// viewConversationList renders the ctrl+l conversation browser: list
+// conversations, enter/resume, n/new, x/remove, esc back to the chat.
+func (m *model) viewConversationList() string {
+	var b strings.Builder
+	b.WriteString("Conversations  (enter: resume · n: new · x: remove · ctrl+b in chat: branch · ctrl+e in chat: edit last directive · ctrl+r in chat: push-to-talk · esc: back)\n\n")
+	if len(m.convoList) == 0 {
+		b.WriteString("  (none yet)\n")
+	}
+	for i, c := range m.convoList {
+		cursor := "  "
+		if i == m.convoCursor {
+			cursor = "> "
+		}
+		b.WriteString(fmt.Sprintf("%s%s  %s\n", cursor, c.CreatedAt.Format("2006-01-02 15:04"), c.Title))
+	}
+	return panelStyle.Copy().Width(m.width - 2).Height(m.height - 2).Render(b.String())
+}
+
 func main() {
+	resume := flag.String("resume", "", "resume a conversation by ID")
+	flag.Parse()
+
 	f, err := tea.LogToFile("skyscope.log", "debug")
-	if err != nil { os.Exit(1) }
+	if err != nil {
+		os.Exit(1)
+	}
 	defer f.Close()
 
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
-	if _, err := p.Run(); err != nil { log.Fatalf("Fatal error: %v", err) }
+	p := tea.NewProgram(initialModel(*resume), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		log.Fatalf("Fatal error: %v", err)
+	}
 }
 GOEOF
 
@@ -380,7 +3086,7 @@ print_info "--- HOW TO RUN ---"
 echo "1. Your Ollama service is confirmed to be running."
 echo "2. Ensure you have the required model: 'ollama pull $OLLAMA_MODEL'"
 print_warning "The VERY first time Coqui TTS runs, it will download voice models. This can take several minutes and appear frozen. Please be patient."
-print_warning "\e[1;31mSECURITY WARNING: The [TOOL:EXECUTE] feature allows the AI to run shell commands. Run this in a sandboxed environment and be aware of the security implications.\e[0m"
+print_warning "\e[1;31mSECURITY WARNING: Agents whose toolbox includes EXECUTE can run shell commands via a fenced \`\`\`json {\"tool\":\"execute\",\"args\":{...}}\`\`\` call, sandboxed per-agent by pkg/sandbox. Review each agent's sandbox policy in agents.yaml and be aware of the security implications.\e[0m"
 echo ""
 read -p "Press [Enter] to compile and begin the immersive Skyscope Sentinel experience..."
 